@@ -11,5 +11,6 @@ func main() {
 	args := new(Options)
 	if err := flaggy.Parse(args, os.Args[1:]); err != nil {
 		fmt.Println(err)
+		os.Exit(1)
 	}
 }
@@ -14,6 +14,7 @@ import (
 
 	"github.com/vedadiyan/protov/internal/compiler"
 	"github.com/vedadiyan/protov/internal/system/install"
+	"go.lsp.dev/protocol"
 )
 
 const (
@@ -266,7 +267,7 @@ func Format(dir, fileName string) error {
 	return nil
 }
 
-func CompileFile(protoPath, outputDir string) (*compiler.AST, error) {
+func CompileFile(protoPath, outputDir string, layout OutputLayout, importMap compiler.ImportMap, runtime compiler.Runtime, emitTests bool) (*compiler.AST, error) {
 	if err := ValidateProtoFile(protoPath); err != nil {
 		return nil, fmt.Errorf("invalid proto file: %w", err)
 	}
@@ -275,7 +276,7 @@ func CompileFile(protoPath, outputDir string) (*compiler.AST, error) {
 		return nil, fmt.Errorf("invalid output directory: %w", err)
 	}
 
-	ast, err := compiler.Parse(protoPath)
+	ast, err := compiler.Parse(protoPath, importMap)
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
@@ -285,31 +286,63 @@ func CompileFile(protoPath, outputDir string) (*compiler.AST, error) {
 	}
 
 	for _, file := range ast.Files {
-		if err := compileAndWriteFile(file, outputDir); err != nil {
+		printDiagnostics(protoPath, file)
+		dir, err := layout.Dir(outputDir, protoPath, file)
+		if err != nil {
+			return nil, err
+		}
+		if err := compileAndWriteFile(file, dir, runtime); err != nil {
 			return nil, fmt.Errorf("compilation error for %q: %w", file.FileName, err)
 		}
+		if emitTests {
+			if err := compileAndWriteTests(file, dir, runtime); err != nil {
+				return nil, fmt.Errorf("test generation error for %q: %w", file.FileName, err)
+			}
+		}
 
 	}
 
 	return ast, nil
 }
 
-func compileAndWriteFile(file *compiler.File, outputDir string) error {
-	compiled, err := compiler.Compile(file)
+func printDiagnostics(protoPath string, file *compiler.File) {
+	for _, diagnostic := range file.Diagnostics {
+		if diagnostic.Severity != protocol.DiagnosticSeverityWarning {
+			continue
+		}
+		start := diagnostic.Range.Start
+		fmt.Fprintf(os.Stderr, "%s:%d:%d: warning: %s\n", protoPath, start.Line+1, start.Character+1, diagnostic.Message)
+	}
+}
+
+func compileAndWriteFile(file *compiler.File, dir string, runtime compiler.Runtime) error {
+	compiled, err := compiler.Compile(file, runtime)
 	if err != nil {
 		return fmt.Errorf("compiler error: %w", err)
 	}
 
+	return writeGenerated(dir, fmt.Sprintf("%s.pb.go", file.FileName), compiled)
+}
+
+func compileAndWriteTests(file *compiler.File, dir string, runtime compiler.Runtime) error {
+	compiled, err := compiler.CompileTests(file, runtime)
+	if err != nil {
+		return fmt.Errorf("compiler error: %w", err)
+	}
+
+	return writeGenerated(dir, fmt.Sprintf("%s.pb_test.go", file.FileName), compiled)
+}
+
+func writeGenerated(dir string, name string, compiled []byte) error {
 	if len(compiled) == 0 {
 		return ErrEmptyData
 	}
 
-	dir := filepath.Join(outputDir, file.FilePath)
 	if err := EnsureDirectory(dir, 0755); err != nil {
 		return err
 	}
 
-	fileName := SanitizeFilename(fmt.Sprintf("%s.pb.go", file.FileName))
+	fileName := SanitizeFilename(name)
 	if fileName == "" {
 		return ErrInvalidFilename
 	}
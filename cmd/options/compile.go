@@ -3,6 +3,9 @@ package options
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	flaggy "github.com/vedadiyan/flaggy/pkg"
 
@@ -16,9 +19,16 @@ var (
 )
 
 type Compile struct {
-	Files  []string `long:"--file" short:"-f" help:"a list of files to be compiled like: -f a.proto -f b.proto"`
-	Output string   `long:"--out" short:"-o" help:"output directory where the compiled files should be saved"`
-	Help   bool     `long:"help" help:"shows help"`
+	Files     []string `long:"--file" short:"-f" help:"a list of files to be compiled like: -f a.proto -f b.proto"`
+	Output    string   `long:"--out" short:"-o" help:"output directory where the compiled files should be saved"`
+	Check     bool     `long:"--check" help:"fails listing the generated files that are out of date instead of writing them"`
+	Diff      bool     `long:"--diff" help:"prints a unified diff of what would change instead of writing the generated files"`
+	Paths     string   `long:"--paths" help:"where generated files are placed: import (under the go_package path) or source_relative (next to the proto file)"`
+	Module    string   `long:"--module" help:"import path prefix to strip from the go_package path when --paths=import"`
+	GoOpts    []string `long:"--go_opt" help:"maps a proto file to a Go package like --go_opt Mdir/a.proto=example.com/a"`
+	Runtime   string   `long:"--runtime" help:"library the generated messages are built on: protolizer (default) or protobuf-go"`
+	EmitTests bool     `long:"--emit-tests" help:"also writes a _test.go file per proto file with round-trip tests, fuzz targets and benchmarks of its messages"`
+	Help      bool     `long:"help" help:"shows help"`
 }
 
 func (c *Compile) Run() error {
@@ -36,9 +46,47 @@ func (c *Compile) Run() error {
 		return fmt.Errorf("prerequisite check failed: %w", err)
 	}
 
+	if c.Check || c.Diff {
+		return c.verify()
+	}
+
 	return c.compileFiles()
 }
 
+// verify generates into a staging directory and compares the result with
+// the output directory without touching it.
+func (c *Compile) verify() error {
+	dir, err := os.MkdirTemp("", "protov-check-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	staged := *c
+	staged.Output = dir
+	if err := staged.compileFiles(); err != nil {
+		return err
+	}
+
+	var diff io.Writer
+	if c.Diff {
+		diff = os.Stdout
+	}
+	stale, err := CompareGenerated(dir, c.Output, diff)
+	if err != nil {
+		return err
+	}
+
+	return reportStale(stale, c.Check)
+}
+
+func reportStale(stale []string, check bool) error {
+	if !check || len(stale) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n  %s", ErrStale, strings.Join(stale, "\n  "))
+}
+
 func (c *Compile) validate() error {
 	if len(c.Files) == 0 {
 		return ErrNoFiles
@@ -58,6 +106,23 @@ func (c *Compile) validate() error {
 		return fmt.Errorf("invalid output directory: %w", err)
 	}
 
+	if err := c.layout().Validate(); err != nil {
+		return err
+	}
+
+	if _, err := compiler.ParseImportMap(c.GoOpts); err != nil {
+		return err
+	}
+
+	runtime, err := compiler.ParseRuntime(c.Runtime)
+	if err != nil {
+		return err
+	}
+
+	if c.EmitTests && runtime != compiler.RuntimeProtolizer {
+		return compiler.ErrTestsUnsupported
+	}
+
 	return nil
 }
 
@@ -82,19 +147,29 @@ func (c *Compile) compileFiles() error {
 }
 
 func (c *Compile) compileFile(protoPath string) error {
-	ast, err := CompileFile(protoPath, c.Output)
+	importMap, err := compiler.ParseImportMap(c.GoOpts)
 	if err != nil {
 		return err
 	}
 
-	return c.processCodeGeneration(ast)
+	runtime, err := compiler.ParseRuntime(c.Runtime)
+	if err != nil {
+		return err
+	}
+
+	ast, err := CompileFile(protoPath, c.Output, c.layout(), importMap, runtime, c.EmitTests)
+	if err != nil {
+		return err
+	}
+
+	return c.processCodeGeneration(protoPath, ast)
 }
 
-func (c *Compile) processCodeGeneration(ast *compiler.AST) error {
+func (c *Compile) processCodeGeneration(protoPath string, ast *compiler.AST) error {
 	for _, file := range ast.Files {
-		outputDir := c.Output
-		if file.FilePath != "" {
-			outputDir = c.Output + "/" + file.FilePath
+		outputDir, err := c.layout().Dir(c.Output, protoPath, file)
+		if err != nil {
+			return err
 		}
 
 		if err := ProcessServiceCodeGeneration(file, ast, outputDir); err != nil {
@@ -104,3 +179,7 @@ func (c *Compile) processCodeGeneration(ast *compiler.AST) error {
 
 	return nil
 }
+
+func (c *Compile) layout() OutputLayout {
+	return OutputLayout{Paths: c.Paths, Module: c.Module}
+}
@@ -1,40 +1,163 @@
 package options_test
 
 import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/vedadiyan/protov/cmd/options"
+	"github.com/vedadiyan/protov/internal/compiler"
 )
 
 func TestCompile_Run(t *testing.T) {
 	tests := []struct {
-		name    string // description of this test case
-		wantErr bool
+		name      string
+		files     []string
+		output    string
+		runtime   string
+		emitTests bool
+		wantErr   error
 	}{
-		struct {
-			name    string
-			wantErr bool
-		}{
-			name:    "basic             ",
-			wantErr: false,
+		{
+			name:    "no files",
+			output:  t.TempDir(),
+			wantErr: options.ErrNoFiles,
+		},
+		{
+			name:    "no output",
+			files:   []string{"testdata/greeter.proto"},
+			wantErr: options.ErrNoOutput,
+		},
+		{
+			name:    "missing file",
+			files:   []string{"testdata/missing.proto"},
+			output:  t.TempDir(),
+			wantErr: options.ErrFileNotFound,
+		},
+		{
+			name:    "not a proto file",
+			files:   []string{"compile_test.go"},
+			output:  t.TempDir(),
+			wantErr: options.ErrInvalidExtension,
+		},
+		{
+			name:    "unknown runtime",
+			files:   []string{"testdata/greeter.proto"},
+			output:  t.TempDir(),
+			runtime: "gogo",
+			wantErr: compiler.ErrInvalidRuntime,
+		},
+		{
+			name:      "tests for protobuf-go",
+			files:     []string{"testdata/greeter.proto"},
+			output:    t.TempDir(),
+			runtime:   string(compiler.RuntimeProtobufGo),
+			emitTests: true,
+			wantErr:   compiler.ErrTestsUnsupported,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// TODO: construct the receiver type.
-			var c options.Compile
-			c.Files = []string{`C:\Users\Pouya\Desktop\New folder\protolizer\benchmarks\test.proto`}
-			c.Output = `C:\Users\Pouya\Desktop\New folder\protolizer\benchmarks\`
-			gotErr := c.Run()
-			if gotErr != nil {
-				if !tt.wantErr {
-					t.Errorf("Run() failed: %v", gotErr)
-				}
-				return
-			}
-			if tt.wantErr {
-				t.Fatal("Run() succeeded unexpectedly")
+			c := options.Compile{Files: tt.files, Output: tt.output, Runtime: tt.runtime, EmitTests: tt.emitTests}
+			if err := c.Run(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestCompile_RunWritesOutput(t *testing.T) {
+	if err := options.CheckTools([]string{"gofmt", "goimports"}); err != nil {
+		t.Skip(err)
+	}
+	out := t.TempDir()
+	c := options.Compile{Files: []string{"testdata/greeter.proto"}, Output: out}
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	file := filepath.Join(out, "greeter", "greeter.pb.go")
+	if _, err := parser.ParseFile(token.NewFileSet(), file, nil, 0); err != nil {
+		t.Fatalf("generated file is not valid Go: %v", err)
+	}
+}
+
+func TestCompile_RunEmitTests(t *testing.T) {
+	if err := options.CheckTools([]string{"gofmt", "goimports"}); err != nil {
+		t.Skip(err)
+	}
+	out := t.TempDir()
+	c := options.Compile{Files: []string{"testdata/greeter.proto"}, Output: out, EmitTests: true}
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	file := filepath.Join(out, "greeter", "greeter.pb_test.go")
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		t.Fatalf("generated test file is not valid Go: %v", err)
+	}
+	for _, want := range []string{"TestRoundTripHelloRequest", "FuzzDecodeHelloRequest", "BenchmarkEncodeHelloRequest", "BenchmarkDecodeHelloRequest"} {
+		if parsed.Scope.Lookup(want) == nil {
+			t.Errorf("generated test file does not declare %s", want)
+		}
+	}
+}
+
+func TestCompile_RunProtobufGo(t *testing.T) {
+	if err := options.CheckTools([]string{"gofmt", "goimports"}); err != nil {
+		t.Skip(err)
+	}
+	out := t.TempDir()
+	c := options.Compile{
+		Files:   []string{"testdata/greeter.proto"},
+		Output:  out,
+		GoOpts:  []string{"Mtestdata/greeter.proto=example.com/greeter"},
+		Runtime: string(compiler.RuntimeProtobufGo),
+	}
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	file := filepath.Join(out, "example.com", "greeter", "greeter.pb.go")
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+	if err != nil {
+		t.Fatalf("generated file is not valid Go: %v", err)
+	}
+	for _, spec := range parsed.Imports {
+		if strings.Contains(spec.Path.Value, "protolizer") {
+			t.Errorf("generated file imports %s", spec.Path.Value)
+		}
+	}
+}
+
+func TestCompile_RunCheck(t *testing.T) {
+	if err := options.CheckTools([]string{"gofmt", "goimports"}); err != nil {
+		t.Skip(err)
+	}
+	out := t.TempDir()
+	check := options.Compile{Files: []string{"testdata/greeter.proto"}, Output: out, Check: true}
+	if err := check.Run(); !errors.Is(err, options.ErrStale) {
+		t.Fatalf("Run() error = %v, want %v before generating", err, options.ErrStale)
+	}
+	if _, err := os.Stat(filepath.Join(out, "greeter")); !os.IsNotExist(err) {
+		t.Fatalf("--check wrote to the output directory: %v", err)
+	}
+
+	c := options.Compile{Files: []string{"testdata/greeter.proto"}, Output: out}
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if err := check.Run(); err != nil {
+		t.Fatalf("Run() reported fresh output as stale: %v", err)
+	}
+
+	file := filepath.Join(out, "greeter", "greeter.pb.go")
+	if err := os.WriteFile(file, []byte("package greeter\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.Run(); !errors.Is(err, options.ErrStale) || !strings.Contains(err.Error(), "greeter.pb.go") {
+		t.Fatalf("Run() error = %v, want %v listing greeter.pb.go", err, options.ErrStale)
+	}
+}
@@ -0,0 +1,245 @@
+package options
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const DiffContext = 3
+
+var ErrStale = errors.New("generated files are out of date")
+
+type (
+	diffOp struct {
+		kind byte
+		line string
+	}
+)
+
+// CompareGenerated compares every file under generated with the file at the
+// same relative path under target and returns the paths under target of
+// those that are missing or differ. When diff is not nil a unified diff of each
+// stale file is written to it. Files in skip are not compared.
+func CompareGenerated(generated, target string, diff io.Writer, skip ...string) ([]string, error) {
+	stale := make([]string, 0)
+	err := filepath.WalkDir(generated, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(generated, path)
+		if err != nil {
+			return err
+		}
+		for _, name := range skip {
+			if rel == name {
+				return nil
+			}
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		got, err := os.ReadFile(filepath.Join(target, rel))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil && bytes.Equal(got, want) {
+			return nil
+		}
+		name := filepath.ToSlash(filepath.Join(target, rel))
+		stale = append(stale, name)
+		if diff != nil {
+			if _, err := io.WriteString(diff, UnifiedDiff(name, name, got, want)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare generated files: %w", err)
+	}
+	return stale, nil
+}
+
+// UnifiedDiff returns the changes from old to new in unified diff format, or
+// an empty string when they are equal.
+func UnifiedDiff(oldName, newName string, old, new []byte) string {
+	ops := diffLines(splitLines(old), splitLines(new))
+
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		from := max(start-DiffContext, 0)
+		end := start
+		for unchanged := 0; end < len(ops) && unchanged <= 2*DiffContext; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		to := end
+		for to > start && ops[to-1].kind == ' ' {
+			to--
+		}
+		to = min(to+DiffContext, len(ops))
+		writeHunk(&sb, ops, from, to)
+		start = to
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp, from, to int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, op := range ops[from:to] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	if oldCount == 0 {
+		oldLine--
+	}
+	if newCount == 0 {
+		newLine--
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+	for _, op := range ops[from:to] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script between a and b with the linear
+// space variant of Myers' algorithm, which splits the script at the middle
+// snake of the edit graph and recurses on both halves.
+func diffLines(a, b []string) []diffOp {
+	return appendDiff(make([]diffOp, 0, max(len(a), len(b))), a, b)
+}
+
+func appendDiff(ops []diffOp, a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+	default:
+		x, y, u, v := middleSnake(a, b)
+		ops = appendDiff(ops, a[:x], b[:y])
+		for _, line := range a[x:u] {
+			ops = append(ops, diffOp{' ', line})
+		}
+		ops = appendDiff(ops, a[u:], b[v:])
+	}
+	for _, line := range common {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// middleSnake searches the edit graph of a and b forwards from its start and
+// backwards from its end until the two searches overlap, and returns the
+// snake from (x, y) to (u, v) on which they met. The scripts from the start
+// to (x, y) and from (u, v) to the end are each shorter than the whole.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+			if r := delta - k; odd && r >= -(d-1) && r <= d-1 && u+backward[offset+r] >= n {
+				return x, y, u, v
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[n-1-u] == b[m-1-v] {
+				u++
+				v++
+			}
+			backward[offset+k] = u
+			if f := delta - k; !odd && f >= -d && f <= d && u+forward[offset+f] >= n {
+				return n - u, m - v, n - x, m - y
+			}
+		}
+	}
+	return 0, 0, 0, 0
+}
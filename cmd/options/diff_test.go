@@ -0,0 +1,102 @@
+package options_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vedadiyan/protov/cmd/options"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n")
+	new := []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n")
+	want := `--- x.go
++++ x.go
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := options.UnifiedDiff("x.go", "x.go", old, new); got != want {
+		t.Fatalf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := options.UnifiedDiff("x.go", "x.go", old, old); got != "" {
+		t.Fatalf("UnifiedDiff() of equal inputs = %q, want empty", got)
+	}
+}
+
+func TestUnifiedDiffLarge(t *testing.T) {
+	var old, new strings.Builder
+	for i := 0; i < 20000; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		old.WriteString(line)
+		if i%1000 == 0 {
+			new.WriteString("changed " + line)
+		} else {
+			new.WriteString(line)
+		}
+	}
+	diff := options.UnifiedDiff("x.go", "x.go", []byte(old.String()), []byte(new.String()))
+	if removed, added := countPrefix(diff, "-line "), countPrefix(diff, "+changed "); removed != 20 || added != 20 {
+		t.Fatalf("UnifiedDiff() removed %d and added %d lines, want 20 and 20", removed, added)
+	}
+	diff = options.UnifiedDiff("x.go", "x.go", []byte(old.String()), nil)
+	if removed := countPrefix(diff, "-line "); removed != 20000 {
+		t.Fatalf("UnifiedDiff() against an empty file removed %d lines, want 20000", removed)
+	}
+}
+
+func countPrefix(diff, prefix string) int {
+	count := 0
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCompareGenerated(t *testing.T) {
+	generated, target := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		"same.go":    "package x\n",
+		"changed.go": "package x\n\nvar A = 1\n",
+		"missing.go": "package x\n",
+		"go.mod":     "module x\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(generated, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range map[string]string{"same.go": "package x\n", "changed.go": "package x\n"} {
+		if err := os.WriteFile(filepath.Join(target, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var diff bytes.Buffer
+	stale, err := options.CompareGenerated(generated, target, &diff, "go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.ToSlash(filepath.Join(target, "changed.go")), filepath.ToSlash(filepath.Join(target, "missing.go"))}
+	if strings.Join(stale, ",") != strings.Join(want, ",") {
+		t.Fatalf("CompareGenerated() = %v, want %v", stale, want)
+	}
+	if !strings.Contains(diff.String(), "+var A = 1\n") || !strings.Contains(diff.String(), "@@ -0,0 +1,1 @@\n+package x\n") {
+		t.Fatalf("unexpected diff:\n%s", diff.String())
+	}
+}
@@ -0,0 +1,79 @@
+package options
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vedadiyan/protov/internal/compiler"
+)
+
+const (
+	PathsImport         = "import"
+	PathsSourceRelative = "source_relative"
+)
+
+var (
+	ErrInvalidPaths  = errors.New("invalid paths option")
+	ErrModulePrefix  = errors.New("go package is outside of the module prefix")
+	ErrOutsideOutput = errors.New("proto file is outside of the working directory")
+)
+
+// OutputLayout decides where the files generated for a proto file are
+// written, following the paths and module options of protoc-gen-go.
+type OutputLayout struct {
+	// Paths is either PathsImport, which places files under the import
+	// path of their go_package, or PathsSourceRelative, which places them
+	// next to the path of the proto file. Empty means PathsImport.
+	Paths string
+	// Module is stripped from the start of the import path when Paths is
+	// PathsImport.
+	Module string
+}
+
+func (l OutputLayout) Validate() error {
+	switch l.Paths {
+	case "", PathsImport:
+	case PathsSourceRelative:
+		if l.Module != "" {
+			return fmt.Errorf("%w: module prefix cannot be used with %s", ErrInvalidPaths, PathsSourceRelative)
+		}
+	default:
+		return fmt.Errorf("%w: expected %s or %s, got %q", ErrInvalidPaths, PathsImport, PathsSourceRelative, l.Paths)
+	}
+	return nil
+}
+
+// Dir returns the directory under outputDir where the files generated from
+// protoPath are written.
+func (l OutputLayout) Dir(outputDir, protoPath string, file *compiler.File) (string, error) {
+	if l.Paths == PathsSourceRelative {
+		dir := filepath.Dir(protoPath)
+		if filepath.IsAbs(dir) {
+			wd, err := os.Getwd()
+			if err != nil {
+				return "", err
+			}
+			if dir, err = filepath.Rel(wd, dir); err != nil {
+				return "", fmt.Errorf("%w: %v", ErrOutsideOutput, err)
+			}
+		}
+		if dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("%w: %s", ErrOutsideOutput, protoPath)
+		}
+		return filepath.Join(outputDir, dir), nil
+	}
+
+	importPath := file.FilePath
+	if l.Module != "" {
+		prefix := strings.TrimSuffix(l.Module, "/")
+		if importPath != prefix && !strings.HasPrefix(importPath, prefix+"/") {
+			return "", fmt.Errorf("%w: %q does not start with %q", ErrModulePrefix, importPath, prefix)
+		}
+		importPath = strings.TrimPrefix(strings.TrimPrefix(importPath, prefix), "/")
+	}
+	return filepath.Join(outputDir, filepath.FromSlash(path.Clean("/"+importPath))), nil
+}
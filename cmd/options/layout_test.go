@@ -0,0 +1,90 @@
+package options_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/vedadiyan/protov/cmd/options"
+	"github.com/vedadiyan/protov/internal/compiler"
+)
+
+func TestOutputLayout_Dir(t *testing.T) {
+	file := &compiler.File{FilePath: "github.com/org/repo/api/v1"}
+	tests := []struct {
+		name    string
+		layout  options.OutputLayout
+		proto   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "import path",
+			proto: "protos/api.proto",
+			want:  "out/github.com/org/repo/api/v1",
+		},
+		{
+			name:   "module prefix",
+			layout: options.OutputLayout{Paths: options.PathsImport, Module: "github.com/org/repo"},
+			proto:  "protos/api.proto",
+			want:   "out/api/v1",
+		},
+		{
+			name:    "module prefix mismatch",
+			layout:  options.OutputLayout{Module: "github.com/org/other"},
+			proto:   "protos/api.proto",
+			wantErr: options.ErrModulePrefix,
+		},
+		{
+			name:    "module prefix is not a path element",
+			layout:  options.OutputLayout{Module: "github.com/org/re"},
+			proto:   "protos/api.proto",
+			wantErr: options.ErrModulePrefix,
+		},
+		{
+			name:   "source relative",
+			layout: options.OutputLayout{Paths: options.PathsSourceRelative},
+			proto:  "protos/api/v1/api.proto",
+			want:   "out/protos/api/v1",
+		},
+		{
+			name:    "source relative outside of the working directory",
+			layout:  options.OutputLayout{Paths: options.PathsSourceRelative},
+			proto:   "../api.proto",
+			wantErr: options.ErrOutsideOutput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.layout.Dir("out", tt.proto, file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Dir() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != filepath.FromSlash(tt.want) {
+				t.Fatalf("Dir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputLayout_Validate(t *testing.T) {
+	valid := []options.OutputLayout{
+		{},
+		{Paths: options.PathsImport, Module: "github.com/org/repo"},
+		{Paths: options.PathsSourceRelative},
+	}
+	for _, layout := range valid {
+		if err := layout.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", layout, err)
+		}
+	}
+	invalid := []options.OutputLayout{
+		{Paths: "relative"},
+		{Paths: options.PathsSourceRelative, Module: "github.com/org/repo"},
+	}
+	for _, layout := range invalid {
+		if err := layout.Validate(); !errors.Is(err, options.ErrInvalidPaths) {
+			t.Errorf("Validate(%+v) = %v, want %v", layout, err, options.ErrInvalidPaths)
+		}
+	}
+}
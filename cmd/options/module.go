@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -52,6 +53,9 @@ type (
 		BuildFlags   []string          `yaml:"buildFlags"`
 		Environment  map[string]string `yaml:"environment"`
 		Tests        []string          `yaml:"tests"`
+		ImportMap    []string          `yaml:"importMap"`
+		Runtime      string            `yaml:"runtime"`
+		EmitTests    bool              `yaml:"emitTests"`
 	}
 	Config struct {
 		Modules []ModuleConfig `yaml:"modules"`
@@ -66,6 +70,8 @@ type (
 	}
 	ModuleBuild struct {
 		Source bool `long:"--source" help:"builds the module into Go source code"`
+		Check  bool `long:"--check" help:"fails listing the generated sources that are out of date instead of building"`
+		Diff   bool `long:"--diff" help:"prints a unified diff of what would change in the generated sources instead of building"`
 		Help   bool `long:"help" help:"shows help"`
 	}
 	ModuleDockerize struct {
@@ -122,6 +128,19 @@ func (mc *ModuleConfig) Validate() error {
 		}
 	}
 
+	if _, err := compiler.ParseImportMap(mc.ImportMap); err != nil {
+		return err
+	}
+
+	target, err := compiler.ParseRuntime(mc.Runtime)
+	if err != nil {
+		return err
+	}
+
+	if mc.EmitTests && target != compiler.RuntimeProtolizer {
+		return compiler.ErrTestsUnsupported
+	}
+
 	return nil
 }
 
@@ -185,6 +204,18 @@ func (mb *ModuleBuild) Run() error {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
+	if mb.Check || mb.Diff {
+		var diff io.Writer
+		if mb.Diff {
+			diff = os.Stdout
+		}
+		stale, err := Verify(config, diff)
+		if err != nil {
+			return err
+		}
+		return reportStale(stale, mb.Check)
+	}
+
 	return Build(config, mb.Source)
 }
 
@@ -340,6 +371,43 @@ func Build(config *Config, sourceOnly bool) error {
 	return nil
 }
 
+// Verify generates the sources of every module into a staging directory and
+// returns the files under each destination that are missing or differ. The
+// go.mod file is not compared since it is expected to change once the
+// module is tidied.
+func Verify(config *Config, diff io.Writer) ([]string, error) {
+	if config == nil {
+		return nil, fmt.Errorf("%w: config is nil", ErrInvalidConfig)
+	}
+
+	stale := make([]string, 0)
+	for _, module := range config.Modules {
+		files, err := verifyModule(module, diff)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify module %q: %w", module.Name, err)
+		}
+		stale = append(stale, files...)
+	}
+
+	return stale, nil
+}
+
+func verifyModule(module ModuleConfig, diff io.Writer) ([]string, error) {
+	dir, err := os.MkdirTemp("", "protov-check-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	staged := module
+	staged.Destination = dir
+	if err := buildModule(staged, true); err != nil {
+		return nil, err
+	}
+
+	return CompareGenerated(dir, module.Destination, diff, "go.mod")
+}
+
 func buildModule(module ModuleConfig, sourceOnly bool) error {
 	if err := EnsureDirectory(module.Destination, 0755); err != nil {
 		return err
@@ -440,6 +508,16 @@ func compileProtoFiles(module ModuleConfig) ([]*compiler.File, error) {
 		return []*compiler.File{}, nil
 	}
 
+	importMap, err := compiler.ParseImportMap(module.ImportMap)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := compiler.ParseRuntime(module.Runtime)
+	if err != nil {
+		return nil, err
+	}
+
 	var allFiles []*compiler.File
 
 	for _, protoPath := range module.ProtoFiles {
@@ -447,12 +525,12 @@ func compileProtoFiles(module ModuleConfig) ([]*compiler.File, error) {
 			return nil, fmt.Errorf("invalid proto file %q: %w", protoPath, err)
 		}
 
-		ast, err := CompileFile(protoPath, module.Destination)
+		ast, err := CompileFile(protoPath, module.Destination, OutputLayout{}, importMap, target, module.EmitTests)
 		if err != nil {
 			return nil, fmt.Errorf("failed to compile %q: %w", protoPath, err)
 		}
 
-		if err := processCodeGeneration(ast, module.Destination); err != nil {
+		if err := processCodeGeneration(protoPath, ast, module.Destination); err != nil {
 			return nil, err
 		}
 
@@ -462,9 +540,12 @@ func compileProtoFiles(module ModuleConfig) ([]*compiler.File, error) {
 	return allFiles, nil
 }
 
-func processCodeGeneration(ast *compiler.AST, destination string) error {
+func processCodeGeneration(protoPath string, ast *compiler.AST, destination string) error {
 	for _, file := range ast.Files {
-		outputDir := filepath.Join(destination, file.FilePath)
+		outputDir, err := OutputLayout{}.Dir(destination, protoPath, file)
+		if err != nil {
+			return err
+		}
 		if err := ProcessServiceCodeGeneration(file, ast, outputDir); err != nil {
 			return fmt.Errorf("code generation failed: %w", err)
 		}
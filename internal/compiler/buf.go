@@ -14,6 +14,7 @@ import (
 	"github.com/bufbuild/protocompile/linker"
 	"github.com/bufbuild/protocompile/parser"
 	"github.com/bufbuild/protocompile/reporter"
+	"github.com/vedadiyan/protov/internal/protos"
 	"github.com/vedadiyan/protov/internal/system/install"
 	"go.lsp.dev/protocol"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -132,6 +133,10 @@ func (r *Resolver) accessor(f string) (io.ReadCloser, error) {
 	filePath := path.Join(r.Dir, cleanPath)
 
 	data, err := os.ReadFile(filePath)
+	if err != nil && strings.HasPrefix(cleanPath, _optionsImportPrefix) {
+		// Fallback to the bundled protov option definitions
+		data, err = protos.FS.ReadFile(strings.TrimPrefix(cleanPath, _optionsImportPrefix))
+	}
 	if err != nil {
 		// Fallback to standard protoc include directory
 		protoPath, err := install.ProtoPath()
@@ -7,9 +7,11 @@ import (
 	"encoding/base64"
 	"fmt"
 	"maps"
+	"math"
 	"os"
 	"path"
 	"reflect"
+	"strconv"
 	"strings"
 	"text/template"
 	"unicode"
@@ -18,7 +20,9 @@ import (
 	"github.com/bufbuild/protocompile/linker"
 	"github.com/bufbuild/protocompile/protoutil"
 	"github.com/google/uuid"
+	"go.lsp.dev/protocol"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -49,27 +53,130 @@ var (
 	_messageTemplate string
 	//go:embed templates/service.go.tmpl
 	_serviceTemplate string
+	//go:embed templates/validate.go.tmpl
+	_validateTemplate string
+	//go:embed templates/json.go.tmpl
+	_jsonTemplate string
+	//go:embed templates/clone.go.tmpl
+	_cloneTemplate string
+	//go:embed templates/accessors.go.tmpl
+	_accessorsTemplate string
+	//go:embed templates/protobufgo.go.tmpl
+	_protobufGoTemplate string
+	//go:embed templates/wire.go.tmpl
+	_wireTemplate string
+	//go:embed templates/test.go.tmpl
+	_testTemplate string
+	//go:embed templates/pool.go.tmpl
+	_poolTemplate string
+	//go:embed templates/stream.go.tmpl
+	_streamTemplate string
+	//go:embed templates/fieldmask.go.tmpl
+	_fieldMaskTemplate string
+	//go:embed templates/redact.go.tmpl
+	_redactTemplate string
+)
+
+var (
+	_protolizerTemplates = []string{
+		_decodeTemplate,
+		_decodeMapTemplate,
+		_decodeRepeatedTemplate,
+		_encodeTemplate,
+		_encodeMapTemplate,
+		_encodeRepeatedTemplate,
+		_enumTemplate,
+		_isZeroTemplate,
+		_mainTemplate,
+		_messageTemplate,
+		_serviceTemplate,
+		_validateTemplate,
+		_jsonTemplate,
+		_cloneTemplate,
+		_accessorsTemplate,
+		_wireTemplate,
+		_testTemplate,
+		_poolTemplate,
+		_streamTemplate,
+		_fieldMaskTemplate,
+		_redactTemplate,
+	}
+	_protobufGoTemplates = []string{
+		_serviceTemplate,
+		_protobufGoTemplate,
+	}
+)
+
+var (
+	_wellKnownTypes = map[protoreflect.FullName]string{
+		"google.protobuf.Any":         "anypb.Any",
+		"google.protobuf.Duration":    "durationpb.Duration",
+		"google.protobuf.Empty":       "emptypb.Empty",
+		"google.protobuf.FieldMask":   "fieldmaskpb.FieldMask",
+		"google.protobuf.Struct":      "structpb.Struct",
+		"google.protobuf.Value":       "structpb.Value",
+		"google.protobuf.ListValue":   "structpb.ListValue",
+		"google.protobuf.Timestamp":   "timestamppb.Timestamp",
+		"google.protobuf.BoolValue":   "wrapperspb.BoolValue",
+		"google.protobuf.BytesValue":  "wrapperspb.BytesValue",
+		"google.protobuf.DoubleValue": "wrapperspb.DoubleValue",
+		"google.protobuf.FloatValue":  "wrapperspb.FloatValue",
+		"google.protobuf.Int32Value":  "wrapperspb.Int32Value",
+		"google.protobuf.Int64Value":  "wrapperspb.Int64Value",
+		"google.protobuf.StringValue": "wrapperspb.StringValue",
+		"google.protobuf.UInt32Value": "wrapperspb.UInt32Value",
+		"google.protobuf.UInt64Value": "wrapperspb.UInt64Value",
+	}
 )
 
 type (
 	Field struct {
 		Name          string
+		ProtoName     string
+		JSONName      string
 		Type          string
 		BaseType      string
 		KeyBaseType   string
 		IndexBaseType string
 		Options       map[string]any
 		Optional      bool
+		Repeated      bool
+		Packed        bool
+		WellKnown     bool
+		Imported      bool
+		Lazy          bool
+		Sensitive     bool
+		Encrypted     bool
+		GoType        *GoType
+		ProtoType     string
+		KeyProtoType  string
+		Default       string
+		Deterministic bool
 		MarshalledTag string
+		WireTag       string
+		TagSize       int
 		Kind          reflect.Kind
 		Index         reflect.Kind
 		Key           reflect.Kind
 		FieldNum      int
+		Rules         *Rules
+		MapKey        *Field
+		MapValue      *Field
+		// Tags are the struct tags appended to MarshalledTag by the tags
+		// option and the default_tags file option.
+		Tags []StructTag
+		// FullName is the full proto name of the field, which encrypted
+		// fields authenticate their ciphertexts with.
+		FullName string
+		// ValidUTF8 is set on proto3 string fields, which are rejected by
+		// the generated Unmarshal when they are not valid UTF-8.
+		ValidUTF8 bool
 	}
 
 	EnumValue struct {
 		Name   string
 		Number int
+		Alias  bool
 	}
 
 	Enum struct {
@@ -86,17 +193,21 @@ type (
 		Options    map[string]any
 		Descriptor string
 		TypeName   string
-		File       *File
+		// Pooled is set when Acquire and Release helpers are generated
+		// for the message.
+		Pooled bool
+		File   *File
 	}
 
 	Service struct {
-		Name           string
-		Options        map[string]any
-		Descriptor     string
-		Rpcs           []*Rpc
-		RpcOptions     map[string]any
-		CodeGeneration []string
-		File           *File
+		Name             string
+		Options          map[string]any
+		Descriptor       string
+		Rpcs             []*Rpc
+		RpcOptions       map[string]any
+		CodeGeneration   []string
+		ValidateRequests bool
+		File             *File
 	}
 
 	Rpc struct {
@@ -106,19 +217,43 @@ type (
 		Input       string
 		Output      string
 		ServiceName string
+		// PooledInput and PooledOutput are set when the request and
+		// response messages are pooled.
+		PooledInput  bool
+		PooledOutput bool
 	}
 
 	File struct {
-		Dir         string
-		PackageName string
-		FilePath    string
-		Source      string
-		Options     map[string]any
-		Messages    []*Message
-		Services    []*Service
-		Enums       []*Enum
-		Comments    map[string]string
-		FileName    string
+		Dir           string
+		PackageName   string
+		FilePath      string
+		Source        string
+		Options       map[string]any
+		Deterministic bool
+		Messages      []*Message
+		Services      []*Service
+		Enums         []*Enum
+		Comments      map[string]string
+		FileName      string
+		Diagnostics   []protocol.Diagnostic
+		// GoTypeImports maps the qualifiers of the types set with the
+		// go_type option to their import paths.
+		GoTypeImports map[string]string
+		// Encrypted is set when a field of the file has the encrypted
+		// option, for the generated tests to register a cipher.
+		Encrypted bool
+		// DescriptorName is the variable holding the registered descriptor
+		// of the file, RawDescriptor its serialized FileDescriptorProto and
+		// Dependencies the descriptor variables of the imports generated
+		// into the same package.
+		DescriptorName string
+		RawDescriptor  string
+		Dependencies   []string
+		initialisms    map[string]bool
+		defaultTags    []defaultTag
+		importPath     string
+		descriptor     protoreflect.FileDescriptor
+		goPackages     map[string]string
 	}
 
 	AST struct {
@@ -126,22 +261,57 @@ type (
 	}
 )
 
-func Compile(file *File) ([]byte, error) {
-	allTemplates := []string{
-		_decodeTemplate,
-		_decodeMapTemplate,
-		_decodeRepeatedTemplate,
-		_encodeTemplate,
-		_encodeMapTemplate,
-		_encodeRepeatedTemplate,
-		_enumTemplate,
-		_isZeroTemplate,
-		_mainTemplate,
-		_messageTemplate,
-		_serviceTemplate,
+// ElementType returns the Go type of a single value of the field: the element
+// type of repeated fields and the value type of maps.
+func (f *Field) ElementType() string {
+	if f.Kind == reflect.Map {
+		return f.IndexBaseType
+	}
+	return f.BaseType
+}
+
+// IsPointer reports whether a singular scalar field is held behind a pointer
+// to track its presence. Optional bytes fields use a nil slice instead.
+func (f *Field) IsPointer() bool {
+	return f.Optional && f.ProtoType != "message" && f.ProtoType != "bytes"
+}
+
+// FixedSize returns the encoded length of a single value of the field when it
+// does not depend on the value, and 0 otherwise.
+func (f *Field) FixedSize() int {
+	switch f.ProtoType {
+	case "bool":
+		return 1
+	case "fixed32", "sfixed32", "float":
+		return 4
+	case "fixed64", "sfixed64", "double":
+		return 8
+	}
+	return 0
+}
+
+// PooledOutput reports whether an rpc of the service has a pooled response,
+// for which its handlers pool the encodings as well.
+func (service *Service) PooledOutput() bool {
+	for _, rpc := range service.Rpcs {
+		if rpc.PooledOutput {
+			return true
+		}
+	}
+	return false
+}
+
+// Compile generates the Go code of the file for the given runtime.
+func Compile(file *File, runtime Runtime) ([]byte, error) {
+	switch runtime {
+	case RuntimeProtolizer:
+	case RuntimeProtobufGo:
+		return compileProtobufGo(file)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRuntime, runtime)
 	}
 	template := template.New("temp")
-	templates, err := parseTemplates(template, allTemplates...)
+	templates, err := parseTemplates(template, _protolizerTemplates...)
 	if err != nil {
 		return nil, err
 	}
@@ -152,7 +322,28 @@ func Compile(file *File) ([]byte, error) {
 	return out.Bytes(), nil
 }
 
-func Parse(file string) (*AST, error) {
+// CompileTests generates the tests of the messages of the file: round trips
+// of randomly populated messages, fuzz targets for their decoders and
+// encoding and decoding benchmarks. Tests are only generated for the
+// protolizer runtime.
+func CompileTests(file *File, runtime Runtime) ([]byte, error) {
+	if runtime != RuntimeProtolizer {
+		return nil, fmt.Errorf("%w, got %q", ErrTestsUnsupported, runtime)
+	}
+	templates, err := parseTemplates(template.New("temp"), _protolizerTemplates...)
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.NewBuffer([]byte{})
+	if err := templates.ExecuteTemplate(out, "Tests", file); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Parse compiles file and returns its AST. importMap overrides the
+// go_package of the files it lists and may be nil.
+func Parse(file string, importMap ImportMap) (*AST, error) {
 	normalizedFile := strings.ReplaceAll(file, "\\", "/")
 	dir := path.Dir(normalizedFile) + "/"
 
@@ -161,7 +352,7 @@ func Parse(file string) (*AST, error) {
 
 	compiler := protocompile.Compiler{
 		SourceInfoMode: protocompile.SourceInfoExtraOptionLocations | protocompile.SourceInfoExtraComments,
-		Resolver:       NewResolver(dir),
+		Resolver:       protocompile.WithStandardImports(NewResolver(dir)),
 		Symbols:        &symbols,
 		Reporter:       &report,
 	}
@@ -180,6 +371,13 @@ func Parse(file string) (*AST, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to process file %d: %w", i, err)
 		}
+		if err := fileAST.resolveGoPackage(dir, linkedFile, importMap); err != nil {
+			return nil, err
+		}
+		if err := fileAST.nameConflicts(); err != nil {
+			return nil, err
+		}
+		fileAST.resolveImports(dir, linkedFile, importMap)
 		ast.Files[i] = fileAST
 	}
 
@@ -195,10 +393,12 @@ func GetFile(dir string, filePath string, file linker.File) (*File, error) {
 	out.FileName = strings.ReplaceAll(strings.ToLower(out.Source), ".proto", "")
 	protodesc := protodesc.ToFileDescriptorProto(file)
 	out.Comments = GetComments(protodesc, file)
+	if err := out.setDescriptor(strings.TrimPrefix(filePath, dir), file); err != nil {
+		return nil, err
+	}
 
 	if opts, ok := file.Options().(*descriptorpb.FileOptions); ok {
-		out.FilePath = opts.GetGoPackage()
-		_, out.PackageName = path.Split(out.FilePath)
+		out.FilePath, out.PackageName = parseGoPackage(opts.GetGoPackage())
 		proto.RangeExtensions(opts, func(et protoreflect.ExtensionType, a any) bool {
 			key := fmt.Sprintf("%s.%s",
 				et.TypeDescriptor().Parent().FullName().Name(),
@@ -206,6 +406,17 @@ func GetFile(dir string, filePath string, file linker.File) (*File, error) {
 			out.Options[key] = out.getInnerOptions("", a)
 			return true
 		})
+		out.Deterministic, _ = out.Options[_deterministicOption].(bool)
+		out.initialisms = make(map[string]bool)
+		initialisms, _ := out.Options[_initialismsOption].([]any)
+		for _, initialism := range initialisms {
+			out.initialisms[strings.ToUpper(initialism.(string))] = true
+		}
+		defaultTags, err := parseDefaultTags(out.Options[_defaultTagsOption])
+		if err != nil {
+			return nil, fmt.Errorf("invalid default_tags: %w", err)
+		}
+		out.defaultTags = defaultTags
 	}
 
 	messages, err := out.GetMessages(file.Messages(), nil)
@@ -224,7 +435,12 @@ func GetFile(dir string, filePath string, file linker.File) (*File, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get enums: %w", err)
 	}
-	out.Enums = enums
+	nestedEnums, err := out.getNestedEnums(file.Messages())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nested enums: %w", err)
+	}
+	out.Enums = append(enums, nestedEnums...)
+	out.checkTypeNames(file)
 
 	return out, nil
 }
@@ -280,10 +496,12 @@ func (file *File) GetMessage(message protoreflect.MessageDescriptor) (*Message,
 	l := fields.Len()
 
 	out := &Message{
-		Name:       string(name),
+		Name:       goTypeName(name),
 		TypeName:   string(fullName),
 		Fields:     make([]*Field, 0, l),
 		Ignorables: NewIgnorables(),
+		Options:    make(map[string]any),
+		Pooled:     isPooled(message),
 		File:       file,
 	}
 
@@ -302,10 +520,12 @@ func (file *File) GetMessage(message protoreflect.MessageDescriptor) (*Message,
 		return out, nil
 	}
 
+	names := file.resolveFieldNames(message)
+	file.checkJSONNames(message)
 	for i := 0; i < l; i++ {
 		fieldDescriptor := fields.Get(i)
 
-		field, err := file.GetField(fieldDescriptor)
+		field, err := file.getField(fieldDescriptor, names[i])
 		if err != nil {
 			return nil, fmt.Errorf("failed to get field %s: %w", fieldDescriptor.Name(), err)
 		}
@@ -315,20 +535,34 @@ func (file *File) GetMessage(message protoreflect.MessageDescriptor) (*Message,
 			out.Ignorables.Add(value)
 		}
 	}
+	if err := checkTagNames(out.Fields); err != nil {
+		return nil, err
+	}
 
 	return out, nil
 }
 
 func (file *File) GetField(fd protoreflect.FieldDescriptor) (*Field, error) {
+	return file.getField(fd, file.goFieldName(string(fd.Name())))
+}
+
+func (file *File) getField(fd protoreflect.FieldDescriptor, name string) (*Field, error) {
 	fieldType := getKind(fd)
 
 	out := &Field{
-		Name:          toGoName(string(fd.Name())),
+		Name:          name,
+		ProtoName:     string(fd.Name()),
+		JSONName:      fd.JSONName(),
 		Type:          fieldType,
 		BaseType:      cleanType(fieldType),
 		FieldNum:      int(fd.Number()),
 		Optional:      fd.HasOptionalKeyword(),
+		Repeated:      fd.IsList(),
+		Packed:        fd.IsPacked(),
+		ProtoType:     fd.Kind().String(),
+		Default:       getDefault(fd),
 		MarshalledTag: marshalTags(fd),
+		Options:       make(map[string]any),
 	}
 
 	if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok {
@@ -336,26 +570,99 @@ func (file *File) GetField(fd protoreflect.FieldDescriptor) (*Field, error) {
 			key := fmt.Sprintf("%s.%s",
 				et.TypeDescriptor().Parent().FullName().Name(),
 				et.TypeDescriptor().FullName().Name())
-			out.Options[key] = a
+			key = toGoName(key)
+			out.Options[key] = file.getInnerOptions("", a)
 			return true
 		})
 	}
 
+	if value, ok := out.Options[_rulesOption].(map[string]any); ok {
+		rules, err := newRules(fd, name, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules: %w", err)
+		}
+		out.Rules = rules
+	}
+
 	switch {
 	case fd.IsMap():
+		mapKey, err := file.GetField(fd.MapKey())
+		if err != nil {
+			return nil, fmt.Errorf("invalid map key: %w", err)
+		}
+		mapValue, err := file.GetField(fd.MapValue())
+		if err != nil {
+			return nil, fmt.Errorf("invalid map value: %w", err)
+		}
+		out.MapKey = mapKey
+		out.MapValue = mapValue
+		out.Deterministic = file.Deterministic
 		out.Kind = reflect.Map
-		out.Index = getReflectedKind(fd.MapKey().Kind())
-		out.Key = getReflectedKind(fd.MapValue().Kind())
+		out.Key = getReflectedKind(fd.MapKey().Kind())
+		out.Index = getReflectedKind(fd.MapValue().Kind())
 		out.KeyBaseType = cleanType(getKind(fd.MapKey()))
 		out.IndexBaseType = cleanType(getKind(fd.MapValue()))
+		out.ProtoType = fd.MapValue().Kind().String()
+		out.KeyProtoType = fd.MapKey().Kind().String()
+		out.WellKnown = isWellKnown(fd.MapValue())
 
 	case fd.IsList():
 		out.Kind = reflect.Array
 		out.Index = getReflectedKind(fd.Kind())
 		out.IndexBaseType = cleanType(fieldType)
+		out.WellKnown = isWellKnown(fd)
 
 	default:
 		out.Kind = getReflectedKind(fd.Kind())
+		out.WellKnown = isWellKnown(fd)
+	}
+	out.Lazy = boolOption(fd.Options(), _lazyOption)
+	if out.Lazy && out.Kind != reflect.Struct {
+		return nil, fmt.Errorf("lazy is only supported on singular message fields")
+	}
+	if out.Lazy && out.Rules != nil {
+		out.Rules.Unset = fmt.Sprintf("%s == nil && x.lazy%s == nil", out.Rules.Field, name)
+	}
+	out.Sensitive = boolOption(fd.Options(), _sensitiveOption)
+	if value, ok := out.Options[_goTypeOption].(map[string]any); ok {
+		goType, err := newGoType(fd, value)
+		if err != nil {
+			return nil, err
+		}
+		if err := file.addImport(goType); err != nil {
+			return nil, err
+		}
+		out.GoType = goType
+		out.Type = goType.Name
+		out.BaseType = goType.Name
+		if out.Rules != nil {
+			if out.Converted() {
+				out.Rules.Unset = out.Rules.Field + " == " + out.GoZero()
+			}
+			out.Rules.Field = out.WireValue(out.Rules.Field)
+			out.Rules.Value = out.Rules.Field
+		}
+	}
+	if boolOption(fd.Options(), _encryptedOption) {
+		if err := checkEncrypted(fd, out); err != nil {
+			return nil, err
+		}
+		out.Encrypted = true
+		file.Encrypted = true
+	}
+	tags, err := file.structTags(fd, out.Options)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		out.MarshalledTag += " " + tag.String()
+	}
+	out.Tags = tags
+	out.WireTag, out.TagSize = wireTag(fd)
+	out.FullName = string(fd.FullName())
+	out.ValidUTF8 = fd.Kind() == protoreflect.StringKind && fd.Syntax() == protoreflect.Proto3
+	if md := fd.Message(); md != nil && !fd.IsMap() {
+		out.Imported = md.ParentFile().Path() != fd.ParentFile().Path()
 	}
 
 	return out, nil
@@ -381,6 +688,29 @@ func (file *File) GetEnums(md protoreflect.EnumDescriptors) ([]*Enum, error) {
 	return out, nil
 }
 
+// getNestedEnums collects the enums declared inside messages. Like nested
+// messages they are flattened into the package under their bare name.
+func (file *File) getNestedEnums(md protoreflect.MessageDescriptors) ([]*Enum, error) {
+	out := make([]*Enum, 0)
+	for i := 0; i < md.Len(); i++ {
+		messageDescriptor := md.Get(i)
+		if messageDescriptor.IsMapEntry() {
+			continue
+		}
+		enums, err := file.GetEnums(messageDescriptor.Enums())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get enums in %s: %w", messageDescriptor.Name(), err)
+		}
+		out = append(out, enums...)
+		nested, err := file.getNestedEnums(messageDescriptor.Messages())
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, nested...)
+	}
+	return out, nil
+}
+
 func (file *File) getEnum(enum protoreflect.EnumDescriptor) (*Enum, error) {
 	name := enum.Name()
 	ed := enum.Values()
@@ -390,7 +720,7 @@ func (file *File) getEnum(enum protoreflect.EnumDescriptor) (*Enum, error) {
 	}
 
 	out := &Enum{
-		Name:   string(name),
+		Name:   goTypeName(name),
 		Values: make([]*EnumValue, 0, l),
 		File:   file,
 	}
@@ -405,11 +735,16 @@ func (file *File) getEnum(enum protoreflect.EnumDescriptor) (*Enum, error) {
 		})
 	}
 
+	numbers := make(map[int]struct{})
 	for i := 0; i < l; i++ {
 		evd := ed.Get(i)
+		number := int(evd.Number())
+		_, alias := numbers[number]
+		numbers[number] = struct{}{}
 		out.Values = append(out.Values, &EnumValue{
 			Name:   string(evd.Name()),
-			Number: int(evd.Number()),
+			Number: number,
+			Alias:  alias,
 		})
 	}
 
@@ -492,6 +827,7 @@ func (file *File) GetService(n int, service protoreflect.ServiceDescriptor) (*Se
 			return true
 		})
 	}
+	out.ValidateRequests, _ = out.Options[_validateRequestsOption].(bool)
 
 	if l == 0 {
 		return out, nil
@@ -524,10 +860,13 @@ func (file *File) GetRpc(path string, serviceName string, fd protoreflect.Method
 
 	out := &Rpc{
 		Name:        string(fd.Name()),
-		Input:       string(input),
-		Output:      string(output),
+		Input:       goTypeName(input),
+		Output:      goTypeName(output),
 		Options:     make(map[string]any),
 		ServiceName: serviceName,
+
+		PooledInput:  isPooled(fd.Input()),
+		PooledOutput: isPooled(fd.Output()),
 	}
 
 	if opts, ok := fd.Options().(*descriptorpb.MethodOptions); ok {
@@ -653,6 +992,35 @@ func canBeIgnored(fd protoreflect.FieldDescriptor) (bool, string) {
 	return false, ""
 }
 
+// wireTag returns the tag of the field as a list of Go byte literals and its
+// length. Maps and packed fields are length-delimited.
+func wireTag(fd protoreflect.FieldDescriptor) (string, int) {
+	var wireType protowire.Type
+	switch {
+	case fd.IsMap() || fd.IsPacked():
+		wireType = protowire.BytesType
+	default:
+		switch fd.Kind() {
+		case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+			wireType = protowire.Fixed32Type
+		case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+			wireType = protowire.Fixed64Type
+		case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind:
+			wireType = protowire.BytesType
+		case protoreflect.GroupKind:
+			wireType = protowire.StartGroupType
+		default:
+			wireType = protowire.VarintType
+		}
+	}
+	tag := protowire.AppendTag(nil, fd.Number(), wireType)
+	literals := make([]string, len(tag))
+	for i, b := range tag {
+		literals[i] = fmt.Sprintf("0x%02x", b)
+	}
+	return strings.Join(literals, ", "), len(tag)
+}
+
 func cleanType(typ string) string {
 	typ = strings.ReplaceAll(typ, "*", "")
 	typ = strings.ReplaceAll(typ, "[]", "")
@@ -677,7 +1045,7 @@ func getKind(fd protoreflect.FieldDescriptor) string {
 	case protoreflect.BoolKind:
 		baseType = "bool"
 	case protoreflect.EnumKind:
-		baseType = string(fd.Enum().Name())
+		baseType = goTypeName(fd.Enum().Name())
 	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind, protoreflect.Fixed32Kind:
 		baseType = "int"
 	case protoreflect.Uint32Kind:
@@ -693,9 +1061,20 @@ func getKind(fd protoreflect.FieldDescriptor) string {
 	case protoreflect.StringKind:
 		baseType = "string"
 	case protoreflect.BytesKind:
+		if fd.IsList() {
+			return "[][]byte"
+		}
 		return "[]byte"
 	case protoreflect.MessageKind:
-		baseType = string(fd.Message().Name())
+		if wellKnown, ok := _wellKnownTypes[fd.Message().FullName()]; ok {
+			// Well-known types are backed by pointers to the protobuf-go
+			// types in every position, including repeated fields
+			if fd.IsList() {
+				return "[]*" + wellKnown
+			}
+			return "*" + wellKnown
+		}
+		baseType = goTypeName(fd.Message().Name())
 	case protoreflect.GroupKind:
 		return "interface{}"
 	default:
@@ -705,6 +1084,54 @@ func getKind(fd protoreflect.FieldDescriptor) string {
 	return prefix + baseType
 }
 
+func isWellKnown(fd protoreflect.FieldDescriptor) bool {
+	if fd.Kind() != protoreflect.MessageKind {
+		return false
+	}
+	_, ok := _wellKnownTypes[fd.Message().FullName()]
+	return ok
+}
+
+// getDefault returns the Go expression that getters fall back to when a field
+// is not set, which is either its declared default or its zero value
+func getDefault(fd protoreflect.FieldDescriptor) string {
+	if fd.IsList() || fd.IsMap() {
+		return "nil"
+	}
+	value := fd.Default()
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(value.Bool())
+	case protoreflect.EnumKind:
+		return fmt.Sprintf("%s(%d)", goTypeName(fd.Enum().Name()), value.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(value.Int(), 10)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		switch f := value.Float(); {
+		case math.IsNaN(f):
+			return "math.NaN()"
+		case math.IsInf(f, 1):
+			return "math.Inf(1)"
+		case math.IsInf(f, -1):
+			return "math.Inf(-1)"
+		default:
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case protoreflect.BytesKind:
+		if fd.HasDefault() {
+			return fmt.Sprintf("[]byte(%s)", strconv.Quote(string(value.Bytes())))
+		}
+		return "nil"
+	default:
+		return "nil"
+	}
+}
+
 func getReflectedKind(k protoreflect.Kind) reflect.Kind {
 	switch k {
 	case protoreflect.BoolKind:
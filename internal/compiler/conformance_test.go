@@ -0,0 +1,210 @@
+package compiler
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const _conformanceDir = "testdata/conformance"
+
+// TestConformance generates code for the conformance corpus into a scratch
+// module and runs the harness in testdata/conformance/harness against it.
+// The harness cross-checks the generated encoders and decoders against
+// dynamicpb and runs next to the tests generated for every file. Set
+// PROTOV_FUZZTIME to also fuzz the generated decoders and PROTOV_BENCHTIME
+// to log how the generated Unmarshal and MarshalAppend compare with the
+// protolizer codec. The harness needs goimports and fetches the
+// dependencies of the scratch module, so it only runs when
+// PROTOV_CONFORMANCE is set and then fails without them.
+func TestConformance(t *testing.T) {
+	if os.Getenv("PROTOV_CONFORMANCE") == "" {
+		t.Skip("set PROTOV_CONFORMANCE to run the conformance harness")
+	}
+	if _, err := exec.LookPath("goimports"); err != nil {
+		t.Fatal("goimports is required to prune the generated imports")
+	}
+
+	dir := t.TempDir()
+	protos, err := filepath.Glob(filepath.Join(_conformanceDir, "*.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := new(descriptorpb.FileDescriptorSet)
+	seen := make(map[string]bool)
+	messages := make(map[string]string)
+	for _, file := range protos {
+		ast, err := Parse(file, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := Compile(ast.Files[0], RuntimeProtolizer)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ast.Files[0].FileName+".pb.go"), code, 0644); err != nil {
+			t.Fatal(err)
+		}
+		tests, err := CompileTests(ast.Files[0], RuntimeProtolizer)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		writeFile(t, filepath.Join(dir, ast.Files[0].FileName+".pb_test.go"), tests)
+		if err := addDescriptors(set, seen, file, ast.Files[0]); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		for _, message := range ast.Files[0].Messages {
+			// The random values encoded by protobuf-go are not
+			// ciphertexts, so messages with encrypted fields are only
+			// checked by their own tests.
+			if slices.ContainsFunc(message.Fields, func(f *Field) bool { return f.Encrypted }) {
+				continue
+			}
+			messages[message.TypeName] = message.Name
+		}
+	}
+
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "descriptor.binpb"), data)
+	writeFile(t, filepath.Join(dir, "messages_test.go"), messageRegistry(messages))
+	harness, err := filepath.Glob(filepath.Join(_conformanceDir, "harness", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range harness {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, filepath.Base(file)), data)
+	}
+	_, self, _, _ := runtime.Caller(0)
+	root := filepath.Join(filepath.Dir(self), "..", "..")
+	writeFile(t, filepath.Join(dir, "go.mod"), []byte(fmt.Sprintf(`module conformance
+
+go 1.23.0
+
+require github.com/vedadiyan/protov v0.0.0
+
+replace github.com/vedadiyan/protov => %s
+`, root)))
+
+	run(t, dir, "gofmt", "-w", ".")
+	run(t, dir, "goimports", "-w", ".")
+	if out, err := command(dir, "go", "mod", "tidy"); err != nil {
+		t.Fatalf("conformance dependencies are unavailable: %v\n%s", err, out)
+	}
+	run(t, dir, "go", "test", "-count=1", "./...")
+	if fuzztime := os.Getenv("PROTOV_FUZZTIME"); fuzztime != "" {
+		run(t, dir, "go", "test", "-run=^$", "-fuzz=^FuzzDecode$", "-fuzztime="+fuzztime)
+	}
+	if benchtime := os.Getenv("PROTOV_BENCHTIME"); benchtime != "" {
+		out, err := command(dir, "go", "test", "-run=^$", "-bench=.", "-benchmem", "-benchtime="+benchtime)
+		if err != nil {
+			t.Fatalf("benchmarks failed: %v\n%s", err, out)
+		}
+		t.Logf("%s", out)
+	}
+}
+
+// addDescriptors adds file and its transitive imports to set. The top-level
+// messages of file are taken from the descriptors embedded by GetMessages so
+// that the harness checks the generated code against what it advertises.
+func addDescriptors(set *descriptorpb.FileDescriptorSet, seen map[string]bool, file string, out *File) error {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(NewResolver(filepath.Dir(file) + "/")),
+	}
+	files, err := compiler.Compile(context.TODO(), filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	embedded := make(map[string]*descriptorpb.DescriptorProto)
+	for _, message := range out.Messages {
+		data, err := base64.StdEncoding.DecodeString(message.Descriptor)
+		if err != nil {
+			return err
+		}
+		// The protov options of the embedded descriptor are not needed by
+		// the harness, and their extensions are not registered here.
+		descriptor := new(descriptorpb.DescriptorProto)
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, descriptor); err != nil {
+			return err
+		}
+		embedded[message.TypeName] = descriptor
+	}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		fdp := protodesc.ToFileDescriptorProto(fd)
+		for i, message := range fdp.MessageType {
+			name := string(fd.Package().Append(protoreflect.Name(message.GetName())))
+			if descriptor, ok := embedded[name]; ok && fd.Path() == files[0].Path() {
+				fdp.MessageType[i] = descriptor
+			}
+		}
+		set.File = append(set.File, fdp)
+	}
+	add(files[0].(linker.File))
+	return nil
+}
+
+func messageRegistry(messages map[string]string) []byte {
+	names := make([]string, 0, len(messages))
+	for name := range messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("package gen\n\nvar _messages = map[string]func() generated{\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "\t%q: func() generated { return new(%s) },\n", name, messages[name])
+	}
+	sb.WriteString("}\n")
+	return []byte(sb.String())
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func run(t *testing.T, dir string, name string, args ...string) {
+	t.Helper()
+	if out, err := command(dir, name, args...); err != nil {
+		t.Fatalf("%s %s: %v\n%s", name, strings.Join(args, " "), err, out)
+	}
+}
+
+func command(dir string, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	return cmd.CombinedOutput()
+}
@@ -0,0 +1,71 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// setDescriptor embeds the descriptor of the file, without source
+// information, to be registered under importPath, the path other files
+// import it by.
+func (file *File) setDescriptor(importPath string, fd protoreflect.FileDescriptor) error {
+	fdp := protodesc.ToFileDescriptorProto(fd)
+	fdp.Name = proto.String(importPath)
+	fdp.SourceCodeInfo = nil
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fdp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal descriptor of %s: %w", importPath, err)
+	}
+	file.importPath = importPath
+	file.descriptor = fd
+	file.DescriptorName = descriptorVar(importPath)
+	file.RawDescriptor = StringToGoByteArray(string(data))
+	return nil
+}
+
+// resolveImports records the Go package of the file and of every file it
+// imports, directly or not, and lists the descriptor variables of the direct
+// imports generated into the same Go package, which must be registered
+// before it.
+func (file *File) resolveImports(dir string, fd protoreflect.FileDescriptor, importMap ImportMap) {
+	file.goPackages = map[string]string{file.importPath: file.FilePath + ";" + file.PackageName}
+	var walk func(fd protoreflect.FileDescriptor, direct bool)
+	walk = func(fd protoreflect.FileDescriptor, direct bool) {
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			imported := imports.Get(i).FileDescriptor
+			if _, ok := file.goPackages[imported.Path()]; ok || imported.IsPlaceholder() {
+				continue
+			}
+			dep := &File{Source: imported.Path()}
+			if opts, ok := imported.Options().(*descriptorpb.FileOptions); ok {
+				dep.FilePath, dep.PackageName = parseGoPackage(opts.GetGoPackage())
+			}
+			if err := dep.resolveGoPackage(dir, imported, importMap); err == nil {
+				file.goPackages[imported.Path()] = dep.FilePath + ";" + dep.PackageName
+				if direct && dep.FilePath == file.FilePath && dep.PackageName == file.PackageName {
+					file.Dependencies = append(file.Dependencies, descriptorVar(imported.Path()))
+				}
+			}
+			walk(imported, false)
+		}
+	}
+	walk(fd, true)
+}
+
+// descriptorVar returns the name of the variable holding the registered
+// descriptor of the proto file at importPath, as protoc-gen-go names it.
+func descriptorVar(importPath string) string {
+	return "File_" + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, importPath)
+}
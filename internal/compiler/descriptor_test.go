@@ -0,0 +1,48 @@
+package compiler
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDescriptorVar(t *testing.T) {
+	tests := map[string]string{
+		"scalars.proto":         "File_scalars_proto",
+		"acme/v1/billing.proto": "File_acme_v1_billing_proto",
+		"with-dash.proto":       "File_with_dash_proto",
+	}
+	for path, want := range tests {
+		if got := descriptorVar(path); got != want {
+			t.Errorf("descriptorVar(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestResolveImports(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		importMap ImportMap
+		want      []string
+	}{
+		{"same package", "testdata/conformance/collections.proto", nil, []string{"File_scalars_proto"}},
+		{"mapped elsewhere", "testdata/conformance/collections.proto", ImportMap{"scalars.proto": "example.com/scalars"}, nil},
+		{"well-known imports", "testdata/conformance/wellknown.proto", nil, nil},
+		{"no imports", "testdata/conformance/scalars.proto", nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := Parse(test.file, test.importMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file := ast.Files[0]
+			if !slices.Equal(file.Dependencies, test.want) {
+				t.Errorf("Dependencies = %v, want %v", file.Dependencies, test.want)
+			}
+			if file.RawDescriptor == "" {
+				t.Error("RawDescriptor is empty")
+			}
+		})
+	}
+}
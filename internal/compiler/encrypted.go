@@ -0,0 +1,22 @@
+package compiler
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// checkEncrypted reports whether the encrypted option can be set on the
+// field. The ciphertext replaces the value on the wire, so only values that
+// are written as they are, and left out when empty, can be encrypted.
+func checkEncrypted(fd protoreflect.FieldDescriptor, field *Field) error {
+	switch {
+	case fd.Kind() != protoreflect.StringKind && fd.Kind() != protoreflect.BytesKind:
+		return fmt.Errorf("encrypted only applies to string and bytes fields")
+	case fd.IsList() || fd.IsMap() || fd.HasPresence():
+		return fmt.Errorf("encrypted only applies to singular fields without presence")
+	case field.GoType != nil:
+		return fmt.Errorf("encrypted cannot be combined with go_type")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncryptedErrors(t *testing.T) {
+	tests := map[string]struct {
+		field string
+		err   string
+	}{
+		"scalar": {
+			field: `int64 amount = 1 [(protov.encrypted) = true];`,
+			err:   "only applies to string and bytes fields",
+		},
+		"repeated": {
+			field: `repeated string emails = 1 [(protov.encrypted) = true];`,
+			err:   "only applies to singular fields",
+		},
+		"optional": {
+			field: `optional string email = 1 [(protov.encrypted) = true];`,
+			err:   "only applies to singular fields",
+		},
+		"go_type": {
+			field: `bytes document = 1 [(protov.encrypted) = true, (protov.go_type) = {name: "json.RawMessage", import: "encoding/json"}];`,
+			err:   "cannot be combined with go_type",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseSource(t, `message Outer {
+    `+test.field+`
+}`)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("Parse() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
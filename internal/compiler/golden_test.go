@@ -0,0 +1,222 @@
+package compiler
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const _goldenDir = "testdata/golden"
+
+var (
+	_update = flag.Bool("update", false, "rewrite the golden files with the current compiler output")
+
+	// _goldenRuntimes maps every runtime to the directory of its golden
+	// files, each of which is type-checked as a separate package.
+	_goldenRuntimes = map[Runtime]string{
+		RuntimeProtolizer: _goldenDir,
+		RuntimeProtobufGo: filepath.Join(_goldenDir, string(RuntimeProtobufGo)),
+	}
+
+	// _goldenUnsupported lists the golden protos using options a runtime
+	// rejects, which are expected to fail with ErrUnsupportedOption.
+	_goldenUnsupported = map[Runtime][]string{
+		RuntimeProtobufGo: {"encrypted.proto", "gotype.proto", "proto3.proto", "sensitive.proto", "service.proto", "tags.proto"},
+	}
+
+	// _generatorVersion matches the version header of protoc-gen-go, which
+	// is left out of the comparison so that the goldens only change with
+	// the generated code.
+	_generatorVersion = regexp.MustCompile(`(?m)^// \tprotoc-gen-go v.*$`)
+)
+
+// TestGolden compiles every proto in testdata/golden for each runtime and
+// compares the formatted output with the .pb.go.golden file of the same name
+// in the directory of the runtime, and the generated tests of the protolizer
+// runtime with the .pb_test.go.golden file. Run with -update after changing a
+// template and review the golden diff.
+func TestGolden(t *testing.T) {
+	protos, err := filepath.Glob(filepath.Join(_goldenDir, "*.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(protos) == 0 {
+		t.Fatal("no golden protos found")
+	}
+	for runtime, dir := range _goldenRuntimes {
+		for _, file := range protos {
+			t.Run(string(runtime)+"/"+filepath.Base(file), func(t *testing.T) {
+				name := filepath.Join(dir, strings.TrimSuffix(filepath.Base(file), ".proto"))
+				ast, err := Parse(file, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				code, err := Compile(ast.Files[0], runtime)
+				if slices.Contains(_goldenUnsupported[runtime], filepath.Base(file)) {
+					if !errors.Is(err, ErrUnsupportedOption) {
+						t.Fatalf("Compile error = %v, want %v", err, ErrUnsupportedOption)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				compareGolden(t, name+".pb.go.golden", code)
+				if runtime != RuntimeProtolizer {
+					return
+				}
+				tests, err := CompileTests(ast.Files[0], runtime)
+				if err != nil {
+					t.Fatal(err)
+				}
+				compareGolden(t, name+".pb_test.go.golden", tests)
+			})
+		}
+	}
+}
+
+// compareGolden formats code and compares it with the golden file, or
+// rewrites the golden file with -update.
+func compareGolden(t *testing.T, golden string, code []byte) {
+	t.Helper()
+	got, err := format.Source(code)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	if *_update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(normalizeGolden(got), normalizeGolden(want)) {
+		t.Errorf("generated code differs from %s (run go test -update and review the diff)\n%s", golden, lineDiff(want, got))
+	}
+}
+
+func normalizeGolden(code []byte) []byte {
+	return _generatorVersion.ReplaceAll(code, []byte("// \tprotoc-gen-go"))
+}
+
+// TestGoldenTypeCheck type-checks the golden outputs of each runtime as one
+// package. Only the packages in _goldenUnresolved may fail to import, and
+// the identifiers they declare are left unchecked; every other import must
+// resolve from this module and every other type error fails the test. The
+// goldens are not pruned by goimports, so unused imports are tolerated.
+func TestGoldenTypeCheck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping type check in short mode")
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The imports must resolve from the module cache, not the network.
+	t.Setenv("GOPROXY", "off")
+	for runtime, goldenDir := range _goldenRuntimes {
+		t.Run(string(runtime), func(t *testing.T) {
+			goldens, err := filepath.Glob(filepath.Join(goldenDir, "*.go.golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fset := token.NewFileSet()
+			files := make([]*ast.File, 0, len(goldens))
+			for _, golden := range goldens {
+				file, err := parser.ParseFile(fset, golden, nil, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, file)
+			}
+			config := types.Config{
+				Importer: sourceImporter{importer.ForCompiler(fset, "source", nil).(types.ImporterFrom), dir},
+				Error: func(err error) {
+					e := err.(types.Error)
+					if strings.Contains(e.Msg, "imported and not used") {
+						return
+					}
+					if strings.Contains(e.Msg, "could not import") && unresolvedImport(e.Msg) {
+						return
+					}
+					t.Error(err)
+				},
+			}
+			pkg, _ := config.Check("gen", fset, files, nil)
+			// A package that failed to import is declared empty.
+			resolved := make(map[string]bool)
+			for _, imported := range pkg.Imports() {
+				if imported.Scope().Len() != 0 {
+					resolved[imported.Path()] = true
+				}
+			}
+			for _, path := range []string{"sync", "google.golang.org/protobuf/reflect/protoreflect"} {
+				if !resolved[path] {
+					t.Errorf("%s did not resolve", path)
+				}
+			}
+		})
+	}
+}
+
+// _goldenUnresolved lists the import paths that cannot be resolved from this
+// module. The protolizer runtime is only a dependency of the generated code,
+// whose use of it is checked by the conformance harness.
+var _goldenUnresolved = []string{"github.com/vedadiyan/protolizer"}
+
+func unresolvedImport(msg string) bool {
+	for _, path := range _goldenUnresolved {
+		if strings.Contains(msg, "could not import "+path+" ") || strings.Contains(msg, "could not import "+path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceImporter resolves imports relative to the module of this package so
+// that module dependencies are found alongside the standard library.
+type sourceImporter struct {
+	importer types.ImporterFrom
+	dir      string
+}
+
+func (s sourceImporter) Import(path string) (*types.Package, error) {
+	return s.importer.ImportFrom(path, s.dir, 0)
+}
+
+func lineDiff(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("first difference at line %d:\n- %s\n+ %s", i+1, w, g)
+		}
+	}
+	return ""
+}
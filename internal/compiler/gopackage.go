@@ -0,0 +1,72 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	ErrNoGoPackage    = errors.New("cannot determine the Go package")
+	ErrInvalidMapping = errors.New("invalid import mapping")
+)
+
+// ImportMap maps proto file paths to Go packages in the go_package form,
+// like the M<file>=<import path> options of protoc-gen-go.
+type ImportMap map[string]string
+
+// ParseImportMap parses M<file>=<import path> entries.
+func ParseImportMap(entries []string) (ImportMap, error) {
+	out := make(ImportMap, len(entries))
+	for _, entry := range entries {
+		file, goPackage, ok := strings.Cut(strings.TrimPrefix(entry, "M"), "=")
+		if !strings.HasPrefix(entry, "M") || !ok || file == "" || goPackage == "" {
+			return nil, fmt.Errorf("%w: expected M<file>=<import path>, got %q", ErrInvalidMapping, entry)
+		}
+		out[file] = goPackage
+	}
+	return out, nil
+}
+
+// resolveGoPackage picks the Go package of the file from, in order, the
+// import map, its go_package option and its proto package declaration. The
+// import map is matched against the path the file is read from, resolved
+// against dir, and against its source name.
+func (file *File) resolveGoPackage(dir string, fd protoreflect.FileDescriptor, importMap ImportMap) error {
+	for _, key := range []string{sourcePath(dir, fd.Path()), file.Source} {
+		if goPackage, ok := importMap[key]; ok {
+			file.FilePath, file.PackageName = parseGoPackage(goPackage)
+			return nil
+		}
+	}
+	if file.PackageName != "" {
+		return nil
+	}
+	if pkg := string(fd.Package()); pkg != "" {
+		file.FilePath, file.PackageName = parseGoPackage(strings.ReplaceAll(pkg, ".", "/"))
+		return nil
+	}
+	return fmt.Errorf("%w for %s: set option go_package, declare a package or map the file with M%s=<import path>", ErrNoGoPackage, file.Source, file.Source)
+}
+
+// parseGoPackage splits a go_package value of the form "path;name" into the
+// import path and the package name, which defaults to the last element of
+// the import path.
+func parseGoPackage(goPackage string) (string, string) {
+	importPath, name, ok := strings.Cut(goPackage, ";")
+	if !ok && importPath != "" {
+		name = path.Base(importPath)
+	}
+	return importPath, name
+}
+
+// sourcePath returns the path the resolver reads the proto file at filePath
+// from, relative to dir or not, so that files are matched by their own path
+// even when they are imported from a sibling directory.
+func sourcePath(dir string, filePath string) string {
+	filePath = strings.TrimPrefix(strings.ReplaceAll(filePath, "\\", "/"), dir)
+	return path.Join(dir, strings.TrimPrefix(filePath, "/"))
+}
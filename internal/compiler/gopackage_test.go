@@ -0,0 +1,103 @@
+package compiler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseGoPackage(t *testing.T) {
+	tests := []struct {
+		goPackage  string
+		importPath string
+		name       string
+	}{
+		{"", "", ""},
+		{"github.com/org/repo/api/v1", "github.com/org/repo/api/v1", "v1"},
+		{"github.com/org/repo/api/v1;apiv1", "github.com/org/repo/api/v1", "apiv1"},
+		{"gen", "gen", "gen"},
+		{";apiv1", "", "apiv1"},
+	}
+	for _, tt := range tests {
+		importPath, name := parseGoPackage(tt.goPackage)
+		if importPath != tt.importPath || name != tt.name {
+			t.Errorf("parseGoPackage(%q) = %q, %q, want %q, %q", tt.goPackage, importPath, name, tt.importPath, tt.name)
+		}
+	}
+}
+
+func TestParseImportMap(t *testing.T) {
+	importMap, err := ParseImportMap([]string{"Ma/b.proto=example.com/a/b", "Mc.proto=example.com/c;cpb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if importMap["a/b.proto"] != "example.com/a/b" || importMap["c.proto"] != "example.com/c;cpb" {
+		t.Fatalf("unexpected import map %v", importMap)
+	}
+	for _, entry := range []string{"a.proto=example.com/a", "Ma.proto", "M=example.com/a", "Ma.proto="} {
+		if _, err := ParseImportMap([]string{entry}); !errors.Is(err, ErrInvalidMapping) {
+			t.Errorf("ParseImportMap(%q) error = %v, want %v", entry, err, ErrInvalidMapping)
+		}
+	}
+}
+
+func TestResolveGoPackage(t *testing.T) {
+	tests := []struct {
+		file        string
+		importMap   ImportMap
+		filePath    string
+		packageName string
+		wantErr     error
+	}{
+		{file: "explicit.proto", filePath: "example.com/acme/billing", packageName: "billingpb"},
+		{file: "derived.proto", filePath: "acme/billing/v1", packageName: "v1"},
+		{file: "anonymous.proto", wantErr: ErrNoGoPackage},
+		{
+			file:        "anonymous.proto",
+			importMap:   ImportMap{"anonymous.proto": "example.com/invoices"},
+			filePath:    "example.com/invoices",
+			packageName: "invoices",
+		},
+		{
+			file:        "explicit.proto",
+			importMap:   ImportMap{"testdata/gopackage/explicit.proto": "example.com/override;overridepb"},
+			filePath:    "example.com/override",
+			packageName: "overridepb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			ast, err := Parse("testdata/gopackage/"+tt.file, tt.importMap)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if file := ast.Files[0]; file.FilePath != tt.filePath || file.PackageName != tt.packageName {
+				t.Fatalf("Parse() = %q, %q, want %q, %q", file.FilePath, file.PackageName, tt.filePath, tt.packageName)
+			}
+		})
+	}
+}
+
+func TestResolveImportedGoPackage(t *testing.T) {
+	tests := []struct {
+		name      string
+		importMap ImportMap
+		want      string
+	}{
+		{name: "source path", importMap: ImportMap{"testdata/gopackage/shared/money.proto": "example.com/shared;sharedpb"}, want: "example.com/shared;sharedpb"},
+		{name: "import path", importMap: ImportMap{"../shared/money.proto": "example.com/money"}, want: "example.com/money;money"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := Parse("testdata/gopackage/billing/invoice.proto", tt.importMap)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := ast.Files[0].goPackages["../shared/money.proto"]; got != tt.want {
+				t.Fatalf("Go package of ../shared/money.proto = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// GoType is the Go type a scalar field is declared with through the
+// protov.go_type option, in place of the type derived from its proto type.
+// The generated code converts between the two at the wire boundary, so the
+// encoding of the field is unchanged.
+type GoType struct {
+	// Name is the type as written in the generated code, such as uuid.UUID.
+	Name string
+	// Import is the import path of the package qualifying Name, and Alias
+	// the qualifier it is imported as. Both are empty for a type declared
+	// in the generated package.
+	Import string
+	Alias  string
+	// FromWire names the function converting a wire value to the type and
+	// ToWire the method of the type converting it back. Both are empty when
+	// the underlying type of the type is WireType, in which case Go
+	// conversions are used.
+	FromWire string
+	ToWire   string
+	// WireType is the Go type of the field without the option.
+	WireType string
+}
+
+func newGoType(fd protoreflect.FieldDescriptor, options map[string]any) (*GoType, error) {
+	out := new(GoType)
+	out.Name, _ = options["Name"].(string)
+	out.Import, _ = options["Import"].(string)
+	out.FromWire, _ = options["FromWire"].(string)
+	out.ToWire, _ = options["ToWire"].(string)
+	out.WireType = getKind(fd)
+
+	switch fd.Kind() {
+	case protoreflect.EnumKind, protoreflect.MessageKind, protoreflect.GroupKind:
+		return nil, fmt.Errorf("go_type only applies to scalar fields")
+	}
+	switch {
+	case fd.IsList() || fd.IsMap() || fd.HasOptionalKeyword():
+		return nil, fmt.Errorf("go_type only applies to singular fields without the optional keyword")
+	case fd.HasDefault():
+		return nil, fmt.Errorf("go_type does not support default values")
+	case out.Name == "":
+		return nil, fmt.Errorf("go_type requires a name")
+	case (out.FromWire == "") != (out.ToWire == ""):
+		return nil, fmt.Errorf("go_type requires both from_wire and to_wire, or neither to convert with Go conversions")
+	}
+
+	alias, _, qualified := strings.Cut(out.Name, ".")
+	if qualified != (out.Import != "") {
+		return nil, fmt.Errorf("go_type name %q must be qualified if and only if import is set", out.Name)
+	}
+	if !qualified {
+		return out, nil
+	}
+	if _reservedTypeNames[alias] {
+		return nil, fmt.Errorf("go_type qualifier %q is reserved by the generated code", alias)
+	}
+	if qualifier, _, ok := strings.Cut(out.FromWire, "."); ok && qualifier != alias {
+		return nil, fmt.Errorf("go_type from_wire %q must be qualified by %q", out.FromWire, alias)
+	}
+	out.Alias = alias
+	return out, nil
+}
+
+// addImport records the package of a mapped type so that the generated code
+// imports it, and reports an error if its qualifier is already taken by a
+// different package.
+func (file *File) addImport(goType *GoType) error {
+	if goType.Import == "" {
+		return nil
+	}
+	if file.GoTypeImports == nil {
+		file.GoTypeImports = make(map[string]string)
+	}
+	if path, ok := file.GoTypeImports[goType.Alias]; ok && path != goType.Import {
+		return fmt.Errorf("go_type qualifier %q refers to both %q and %q", goType.Alias, path, goType.Import)
+	}
+	file.GoTypeImports[goType.Alias] = goType.Import
+	return nil
+}
+
+// Converted reports whether the field is mapped to a Go type through
+// converter functions rather than Go conversions.
+func (f *Field) Converted() bool {
+	return f.GoType != nil && f.GoType.ToWire != ""
+}
+
+// GoZero returns the Go expression of the zero value of the Go type of a
+// converted field, which is unset even when its wire value is not empty, as
+// for the zero uuid.UUID. Converted types must therefore be comparable.
+func (f *Field) GoZero() string {
+	return fmt.Sprintf("*new(%s)", f.GoType.Name)
+}
+
+// WireValue returns the Go expression of the wire value of expr, a value of
+// the Go type of the field.
+func (f *Field) WireValue(expr string) string {
+	switch {
+	case f.GoType == nil:
+		return expr
+	case f.Converted():
+		return fmt.Sprintf("%s.%s()", expr, f.GoType.ToWire)
+	default:
+		return fmt.Sprintf("%s(%s)", f.GoType.WireType, expr)
+	}
+}
+
+// Wire returns a copy of the field typed as it would be without the go_type
+// option, for the templates producing or consuming wire values.
+func (f *Field) Wire() *Field {
+	if f.GoType == nil {
+		return f
+	}
+	out := *f
+	out.Type = f.GoType.WireType
+	out.BaseType = cleanType(f.GoType.WireType)
+	out.GoType = nil
+	return &out
+}
@@ -0,0 +1,84 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoTypeErrors(t *testing.T) {
+	tests := map[string]struct {
+		field string
+		err   string
+	}{
+		"message": {
+			field: `Inner inner = 1 [(protov.go_type) = {name: "Money"}];`,
+			err:   "only applies to scalar fields",
+		},
+		"repeated": {
+			field: `repeated int64 amounts = 1 [(protov.go_type) = {name: "Money"}];`,
+			err:   "only applies to singular fields",
+		},
+		"optional": {
+			field: `optional int64 amount = 1 [(protov.go_type) = {name: "Money"}];`,
+			err:   "only applies to singular fields",
+		},
+		"one converter": {
+			field: `int64 amount = 1 [(protov.go_type) = {name: "Money", to_wire: "Cents"}];`,
+			err:   "requires both from_wire and to_wire",
+		},
+		"unqualified import": {
+			field: `int64 amount = 1 [(protov.go_type) = {name: "Money", import: "example.com/money"}];`,
+			err:   "must be qualified if and only if import is set",
+		},
+		"reserved qualifier": {
+			field: `int64 amount = 1 [(protov.go_type) = {name: "proto.Money", import: "example.com/proto"}];`,
+			err:   "reserved by the generated code",
+		},
+		"foreign converter": {
+			field: `string id = 1 [(protov.go_type) = {name: "uuid.UUID", import: "github.com/google/uuid", from_wire: "ids.Parse", to_wire: "String"}];`,
+			err:   `must be qualified by "uuid"`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseSource(t, `message Inner {}
+message Outer {
+    `+test.field+`
+}`)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("Parse() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestGoTypeImportConflict(t *testing.T) {
+	file := new(File)
+	if err := file.addImport(&GoType{Import: "example.com/a/money", Alias: "money"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.addImport(&GoType{Import: "example.com/a/money", Alias: "money"}); err != nil {
+		t.Fatalf("the same import was rejected: %v", err)
+	}
+	if err := file.addImport(&GoType{Import: "example.com/b/money", Alias: "money"}); err == nil {
+		t.Fatal("a qualifier bound to two packages was accepted")
+	}
+}
+
+// parseSource parses a proto3 file importing the codegen options, made of
+// the given declarations.
+func parseSource(t *testing.T, declarations string) (*AST, error) {
+	t.Helper()
+	source := `syntax = "proto3";
+package scratch;
+option go_package = "scratch/gen";
+import "protov/codegen.proto";
+` + declarations + "\n"
+	file := filepath.Join(t.TempDir(), "scratch.proto")
+	if err := os.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Parse(file, nil)
+}
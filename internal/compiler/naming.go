@@ -0,0 +1,244 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.lsp.dev/protocol"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var ErrNameConflict = errors.New("conflicting names")
+
+var (
+	// _reservedTypeNames cannot be used as the name of a generated type
+	// since they would shadow a keyword, a predeclared identifier, a package
+	// imported by the generated code or a variable used in its methods
+	_reservedTypeNames = makeSet(
+		// Keywords
+		"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for",
+		"func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return",
+		"select", "struct", "switch", "type", "var",
+		// Predeclared identifiers
+		"any", "bool", "byte", "comparable", "complex64", "complex128", "error", "float32", "float64",
+		"int", "int8", "int16", "int32", "int64", "rune", "string", "uint", "uint8", "uint16", "uint32",
+		"uint64", "uintptr", "true", "false", "iota", "nil", "append", "cap", "clear", "close",
+		"complex", "copy", "delete", "imag", "len", "make", "max", "min", "new", "panic", "print",
+		"println", "real", "recover",
+		// Imported packages
+		"bytes", "fmt", "io", "slog", "math", "context", "regexp", "slices", "sort", "strconv", "utf8",
+		"protolizer", "metadata", "codecs", "pdk", "memory", "jsonpb", "registry", "validation", "encryption",
+		"wire", "protowire", "proto", "protoreflect", "anypb", "durationpb", "emptypb", "fieldmaskpb", "structpb",
+		"timestamppb", "wrapperspb", "binary", "rand", "testing", "sync", "strings", "fieldmask",
+		// Variables of the generated methods
+		"x", "a", "b", "i", "k", "n", "v", "ok", "err", "out", "src", "other", "data", "value",
+		"field", "buffer", "raw", "entry", "key", "num", "read", "wireType", "names", "w", "r",
+		"m", "size", "keys", "packed", "in", "depth", "t", "f", "fields", "rest", "fn", "name", "nested", "sub", "mask", "path", "tree",
+		"attrs", "items",
+	)
+
+	// _messageMethods are the methods generated for every message, which
+	// fields and their accessors must not collide with
+	_messageMethods = makeSet(
+		"New", "Type", "Marshal", "UnknownFields", "SetUnknownFields", "Encode", "Decode",
+		"Reset", "Clone", "Equal", "Merge", "IsZero", "Validate", "MarshalJSON", "UnmarshalJSON",
+		"MarshalJSONWith", "UnmarshalJSONWith", "WriteJSON", "ReadJSON", "ProtoReflect",
+		"Size", "MarshalAppend", "Unmarshal", "ValidateFieldMask", "MergeFieldMask", "FilterFieldMask",
+		"String", "GoString", "LogValue",
+	)
+
+	_accessorPrefixes = []string{"Get", "Set", "Clear", "Has"}
+)
+
+func makeSet(values ...string) map[string]bool {
+	out := make(map[string]bool, len(values))
+	for _, value := range values {
+		out[value] = true
+	}
+	return out
+}
+
+// goTypeName returns the Go name of a message or enum. Nested types are
+// flattened under their bare name, which is escaped with a trailing
+// underscore when it is reserved.
+func goTypeName(name protoreflect.Name) string {
+	if _reservedTypeNames[string(name)] {
+		return string(name) + "_"
+	}
+	return string(name)
+}
+
+// goFieldName converts a proto field name to an exported Go name,
+// upper-casing the segments listed in the initialisms file option.
+func (file *File) goFieldName(name string) string {
+	segments := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '.'
+	})
+	for i, segment := range segments {
+		if upper := strings.ToUpper(segment); file.initialisms[upper] {
+			segments[i] = upper
+			continue
+		}
+		runes := []rune(segment)
+		runes[0] = unicode.ToUpper(runes[0])
+		segments[i] = string(runes)
+	}
+	out := strings.Join(segments, "")
+	if out == "" || !unicode.IsLetter([]rune(out)[0]) {
+		out = "X" + out
+	}
+	return out
+}
+
+// resolveFieldNames returns the Go names of the fields of message. A name
+// already taken by a generated method, an earlier field or the accessors of
+// an earlier field gets trailing underscores, and the rename is reported as
+// a warning.
+func (file *File) resolveFieldNames(message protoreflect.MessageDescriptor) []string {
+	fields := message.Fields()
+	taken := make(map[string]bool, len(_messageMethods)+fields.Len()*5)
+	for name := range _messageMethods {
+		taken[name] = true
+	}
+	isTaken := func(name string) bool {
+		if taken[name] {
+			return true
+		}
+		for _, prefix := range _accessorPrefixes {
+			if taken[prefix+name] {
+				return true
+			}
+		}
+		return false
+	}
+	out := make([]string, fields.Len())
+	for i := range out {
+		fd := fields.Get(i)
+		base := file.goFieldName(string(fd.Name()))
+		name := base
+		for isTaken(name) {
+			name += "_"
+		}
+		if name != base {
+			file.warn(fd, "field %s of %s is generated as %s since %s is already used", fd.Name(), message.FullName(), name, base)
+		}
+		taken[name] = true
+		for _, prefix := range _accessorPrefixes {
+			taken[prefix+name] = true
+		}
+		out[i] = name
+	}
+	return out
+}
+
+// checkJSONNames reports the fields of message whose JSON name or proto name
+// is the JSON name or the proto name of an earlier field, which the generated
+// JSON methods could not tell apart as they accept both. protoc only rejects
+// some of these, such as custom JSON names shared by two fields.
+func (file *File) checkJSONNames(message protoreflect.MessageDescriptor) {
+	fields := message.Fields()
+	owners := make(map[string]protoreflect.FieldDescriptor, fields.Len()*2)
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		for _, name := range []string{fd.JSONName(), string(fd.Name())} {
+			if owner, ok := owners[name]; ok && owner != fd {
+				file.report(fd, protocol.DiagnosticSeverityError, "fields %s and %s of %s are both read from the JSON key %q", owner.Name(), fd.Name(), message.FullName(), name)
+				break
+			}
+			owners[name] = fd
+		}
+	}
+}
+
+// checkTypeNames reports the package-level identifiers declared by more
+// than one message, enum or service of the file.
+func (file *File) checkTypeNames(fd protoreflect.FileDescriptor) {
+	declared := make(map[string][]protoreflect.Descriptor)
+	declare := func(d protoreflect.Descriptor, names ...string) {
+		for _, name := range names {
+			if n := len(declared[name]); n == 0 || declared[name][n-1] != d {
+				declared[name] = append(declared[name], d)
+			}
+		}
+	}
+	var walk func(messages protoreflect.MessageDescriptors, enums protoreflect.EnumDescriptors)
+	walk = func(messages protoreflect.MessageDescriptors, enums protoreflect.EnumDescriptors) {
+		for i := 0; i < enums.Len(); i++ {
+			enum := enums.Get(i)
+			name := goTypeName(enum.Name())
+			declare(enum, name, name+"_name", name+"_value")
+			values := enum.Values()
+			for j := 0; j < values.Len(); j++ {
+				declare(enum, name+"_"+string(values.Get(j).Name()))
+			}
+		}
+		for i := 0; i < messages.Len(); i++ {
+			message := messages.Get(i)
+			if message.IsMapEntry() {
+				continue
+			}
+			declare(message, goTypeName(message.Name()))
+			walk(message.Messages(), message.Enums())
+		}
+	}
+	walk(fd.Messages(), fd.Enums())
+
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		service := services.Get(i)
+		name := string(service.Name())
+		declare(service, name+"Headers", name+"Transport", name+"ServiceOptions", name+"RpcOptions",
+			name+"HandlerOptions", name+"Server", name+"Service", "Get"+name+"ServiceOptions", "Build"+name)
+		methods := service.Methods()
+		for j := 0; j < methods.Len(); j++ {
+			declare(service, "Get"+name+string(methods.Get(j).Name())+"RpcOptions")
+		}
+	}
+
+	names := make([]string, 0, len(declared))
+	for name, descriptors := range declared {
+		if len(descriptors) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		descriptors := declared[name]
+		file.report(descriptors[1], protocol.DiagnosticSeverityError, "%s is declared by both %s and %s", name, descriptors[0].FullName(), descriptors[1].FullName())
+	}
+}
+
+func (file *File) warn(d protoreflect.Descriptor, format string, args ...any) {
+	file.report(d, protocol.DiagnosticSeverityWarning, format, args...)
+}
+
+func (file *File) report(d protoreflect.Descriptor, severity protocol.DiagnosticSeverity, format string, args ...any) {
+	location := d.ParentFile().SourceLocations().ByDescriptor(d)
+	pos := protocol.Position{
+		Line:      uint32(location.StartLine),
+		Character: uint32(location.StartColumn),
+	}
+	file.Diagnostics = append(file.Diagnostics, protocol.Diagnostic{
+		Range:    protocol.Range{Start: pos, End: pos},
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Source:   file.Source,
+	})
+}
+
+// nameConflicts joins the error diagnostics of the file into an error.
+func (file *File) nameConflicts() error {
+	var conflicts []string
+	for _, diagnostic := range file.Diagnostics {
+		if diagnostic.Severity == protocol.DiagnosticSeverityError {
+			conflicts = append(conflicts, fmt.Sprintf("%s:%d:%d: %s", file.Source, diagnostic.Range.Start.Line+1, diagnostic.Range.Start.Character+1, diagnostic.Message))
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w:\n%s", ErrNameConflict, strings.Join(conflicts, "\n"))
+}
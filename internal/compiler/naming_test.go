@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestResolveFieldNames(t *testing.T) {
+	ast, err := Parse("testdata/golden/naming.proto", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := map[string]uint32{
+		"FooBar_":        28,
+		"Reset_":         31,
+		"GetName_":       33,
+		"UnknownFields_": 34,
+		"Encode_":        35,
+	}
+	diagnostics := ast.Files[0].Diagnostics
+	if len(diagnostics) != len(renamed) {
+		t.Fatalf("expected %d diagnostics, got %v", len(renamed), diagnostics)
+	}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity != protocol.DiagnosticSeverityWarning {
+			t.Errorf("expected a warning, got %v", diagnostic)
+		}
+		name := strings.Fields(diagnostic.Message)[7]
+		line, ok := renamed[name]
+		if !ok || diagnostic.Range.Start.Line != line {
+			t.Errorf("unexpected diagnostic at line %d: %s", diagnostic.Range.Start.Line+1, diagnostic.Message)
+		}
+	}
+}
+
+func TestGoFieldName(t *testing.T) {
+	file := &File{initialisms: map[string]bool{"ID": true, "URL": true}}
+	tests := map[string]string{
+		"user_id":    "UserID",
+		"id":         "ID",
+		"idle":       "Idle",
+		"avatar_url": "AvatarURL",
+		"fooBar":     "FooBar",
+		"_1st":       "X1st",
+		"type":       "Type",
+	}
+	for name, want := range tests {
+		if got := file.goFieldName(name); got != want {
+			t.Errorf("goFieldName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCheckTypeNames(t *testing.T) {
+	_, err := Parse("testdata/naming/clash.proto", nil)
+	if !errors.Is(err, ErrNameConflict) {
+		t.Fatalf("Parse() error = %v, want %v", err, ErrNameConflict)
+	}
+	if !strings.Contains(err.Error(), "clash.proto:14:5: Item is declared by both naming.Order.Item and naming.Cart.Item") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckJSONNames(t *testing.T) {
+	_, err := Parse("testdata/naming/jsonname.proto", nil)
+	if !errors.Is(err, ErrNameConflict) {
+		t.Fatalf("Parse() error = %v, want %v", err, ErrNameConflict)
+	}
+	for _, want := range []string{
+		`jsonname.proto:9:5: fields display_name and displayName of naming.Profile are both read from the JSON key "displayName"`,
+		`jsonname.proto:14:5: fields user_id and owner of naming.Account are both read from the JSON key "user_id"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package compiler
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// File options are keyed by the full name of the extension rather than
+	// its Go name
+	_deterministicOption = "protov.deterministic"
+	_initialismsOption   = "protov.initialisms"
+	_poolMessagesOption  = "protov.pool_messages"
+	_defaultTagsOption   = "protov.default_tags"
+
+	_pooledOption    = "protov.pooled"
+	_lazyOption      = "protov.lazy"
+	_sensitiveOption = "protov.sensitive"
+	_encryptedOption = "protov.encrypted"
+
+	// Like the rules, the go_type and tags options are read from the field
+	// options keyed by Go name
+	_goTypeOption = "ProtovGoType"
+	_tagsOption   = "ProtovTags"
+)
+
+// _redacted replaces the keys of sensitive map fields in the paths of
+// validation errors, like jsonpb.Redacted does their values in logs.
+const _redacted = "[REDACTED]"
+
+// isPooled reports whether acquire and release helpers are generated for the
+// message, either because it sets the pooled option or because its file sets
+// pool_messages.
+func isPooled(md protoreflect.MessageDescriptor) bool {
+	return boolOption(md.Options(), _pooledOption) || boolOption(md.ParentFile().Options(), _poolMessagesOption)
+}
+
+// boolOption returns the value of the boolean custom option with the given
+// full name, or false when it is not set.
+func boolOption(opts proto.Message, name protoreflect.FullName) bool {
+	value := false
+	proto.RangeExtensions(opts, func(et protoreflect.ExtensionType, a any) bool {
+		if et.TypeDescriptor().FullName() != name {
+			return true
+		}
+		value, _ = a.(bool)
+		return false
+	})
+	return value
+}
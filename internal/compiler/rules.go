@@ -0,0 +1,207 @@
+package compiler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	_rulesOption            = "ProtovRules"
+	_validateRequestsOption = "ProtovValidateRequests"
+)
+
+// Rules holds the constraints declared through the protov.rules field option
+// along with the Go expressions the validation templates need to check them.
+type Rules struct {
+	Required    bool
+	Min         *float64
+	Max         *float64
+	MinLen      *uint64
+	MaxLen      *uint64
+	Pattern     string
+	DefinedOnly bool
+	Format      string
+	MinItems    *uint64
+	MaxItems    *uint64
+	Items       *Rules
+
+	Field string
+	Value string
+	// Unset overrides the condition of a missing required value for the
+	// fields whose zero value is not told apart by their wire value.
+	Unset       string
+	Path        string
+	Key         string
+	PatternName string
+	Pointer     bool
+	Collection  bool
+	Bool        bool
+	Numeric     bool
+	Enum        bool
+	String      bool
+	Bytes       bool
+	Message     bool
+}
+
+// HasValueChecks reports whether any constraint applies to the field value
+// itself rather than to its presence.
+func (r *Rules) HasValueChecks() bool {
+	return r.Min != nil || r.Max != nil || r.MinLen != nil || r.MaxLen != nil ||
+		r.Pattern != "" || r.DefinedOnly || r.Format != ""
+}
+
+// HasChecks reports whether the rules produce any validation code.
+func (r *Rules) HasChecks() bool {
+	return r.Required || r.HasValueChecks() || r.MinItems != nil || r.MaxItems != nil ||
+		(r.Items != nil && r.Items.HasChecks())
+}
+
+func newRules(fd protoreflect.FieldDescriptor, name string, options map[string]any) (*Rules, error) {
+	rules := parseRules(options)
+	rules.Field = "x." + name
+	rules.Path = strconv.Quote(string(fd.Name()))
+	rules.PatternName = fmt.Sprintf("_%s_%s_Pattern", fd.ContainingMessage().Name(), name)
+
+	if !fd.IsList() && !fd.IsMap() {
+		rules.Pointer = fd.HasOptionalKeyword() && fd.Kind() != protoreflect.BytesKind || fd.Kind() == protoreflect.MessageKind
+		if err := rules.bind(fd); err != nil {
+			return nil, err
+		}
+		return rules, nil
+	}
+
+	rules.Collection = true
+	if rules.HasValueChecks() {
+		return nil, fmt.Errorf("only required, min_items, max_items and items rules apply to repeated and map fields")
+	}
+	if rules.Items == nil {
+		return rules, nil
+	}
+
+	items := rules.Items
+	items.Field = "item"
+	items.PatternName = rules.PatternName + "Items"
+	element := fd
+	if fd.IsMap() {
+		element = fd.MapValue()
+		items.Key = "key"
+		items.Pointer = element.Kind() == protoreflect.MessageKind
+		items.Path = fmt.Sprintf(`fmt.Sprintf("%s[%%v]", key)`, fd.Name())
+		if boolOption(fd.Options(), _sensitiveOption) {
+			items.Key = "_"
+			items.Path = strconv.Quote(string(fd.Name()) + _redacted)
+		}
+	} else {
+		items.Key = "i"
+		items.Path = fmt.Sprintf(`fmt.Sprintf("%s[%%d]", i)`, fd.Name())
+	}
+	if items.MinItems != nil || items.MaxItems != nil || items.Items != nil {
+		return nil, fmt.Errorf("item rules cannot contain min_items, max_items or items")
+	}
+	if items.Required && element.Kind() == protoreflect.MessageKind && !items.Pointer {
+		return nil, fmt.Errorf("required does not apply to repeated message items")
+	}
+	if err := items.bind(element); err != nil {
+		return nil, fmt.Errorf("invalid item rules: %w", err)
+	}
+	return rules, nil
+}
+
+func parseRules(options map[string]any) *Rules {
+	rules := new(Rules)
+	for key, value := range options {
+		switch key {
+		case "Required":
+			rules.Required, _ = value.(bool)
+		case "Min":
+			if value, ok := value.(float64); ok {
+				rules.Min = &value
+			}
+		case "Max":
+			if value, ok := value.(float64); ok {
+				rules.Max = &value
+			}
+		case "MinLen":
+			if value, ok := value.(uint64); ok {
+				rules.MinLen = &value
+			}
+		case "MaxLen":
+			if value, ok := value.(uint64); ok {
+				rules.MaxLen = &value
+			}
+		case "Pattern":
+			rules.Pattern, _ = value.(string)
+		case "DefinedOnly":
+			rules.DefinedOnly, _ = value.(bool)
+		case "Format":
+			if value, ok := value.(protoreflect.EnumNumber); ok {
+				switch value {
+				case 1:
+					rules.Format = "email"
+				case 2:
+					rules.Format = "uuid"
+				}
+			}
+		case "MinItems":
+			if value, ok := value.(uint64); ok {
+				rules.MinItems = &value
+			}
+		case "MaxItems":
+			if value, ok := value.(uint64); ok {
+				rules.MaxItems = &value
+			}
+		case "Items":
+			if value, ok := value.(map[string]any); ok {
+				rules.Items = parseRules(value)
+			}
+		}
+	}
+	return rules
+}
+
+func (r *Rules) bind(fd protoreflect.FieldDescriptor) error {
+	r.Value = r.Field
+	if r.Pointer {
+		r.Value = "*" + r.Field
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		r.Bool = true
+	case protoreflect.EnumKind:
+		r.Enum = true
+	case protoreflect.StringKind:
+		r.String = true
+	case protoreflect.BytesKind:
+		r.Bytes = true
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		r.Message = true
+	default:
+		r.Numeric = true
+	}
+
+	if r.MinItems != nil || r.MaxItems != nil || r.Items != nil {
+		return fmt.Errorf("min_items, max_items and items only apply to repeated and map fields")
+	}
+	if (r.Min != nil || r.Max != nil) && !r.Numeric && !r.Enum {
+		return fmt.Errorf("min and max only apply to numeric and enum fields")
+	}
+	if (r.MinLen != nil || r.MaxLen != nil) && !r.String && !r.Bytes {
+		return fmt.Errorf("min_len and max_len only apply to string and bytes fields")
+	}
+	if (r.Pattern != "" || r.Format != "") && !r.String {
+		return fmt.Errorf("pattern and format only apply to string fields")
+	}
+	if r.DefinedOnly && !r.Enum {
+		return fmt.Errorf("defined_only only applies to enum fields")
+	}
+	if r.Pattern != "" {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", r.Pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package compiler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"text/template"
+
+	"google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Runtime selects the library the generated messages are built on.
+type Runtime string
+
+const (
+	// RuntimeProtolizer generates messages encoded by protolizer, with the
+	// accessors, validation and JSON methods of protov.
+	RuntimeProtolizer Runtime = "protolizer"
+	// RuntimeProtobufGo generates the same messages as protoc-gen-go, which
+	// only depend on google.golang.org/protobuf.
+	RuntimeProtobufGo Runtime = "protobuf-go"
+)
+
+// _protobufModule is the module whose protoc-gen-go generates the messages
+// of RuntimeProtobufGo.
+const _protobufModule = "google.golang.org/protobuf"
+
+// _protocGenGoVersion pins the release of _protobufModule the protobuf-go
+// runtime is generated with. Its generator is internal to that module and
+// has no compatibility promise, so go.mod requires exactly this version and
+// the compiler refuses to run against any other. To upgrade, bump both
+// together, run go test ./internal/compiler -update and review the golden
+// diff under testdata/golden/protobuf-go.
+const _protocGenGoVersion = "v1.36.9"
+
+// _optionsImportPrefix starts the import paths of the protov option
+// definitions.
+const _optionsImportPrefix = "protov/"
+
+var (
+	ErrInvalidRuntime    = errors.New("invalid runtime")
+	ErrTestsUnsupported  = errors.New("tests are only generated for the protolizer runtime")
+	ErrUnsupportedOption = errors.New("option is not supported by the runtime")
+	ErrProtobufVersion   = errors.New("unsupported google.golang.org/protobuf version")
+)
+
+// ParseRuntime validates the name of a runtime. An empty name selects
+// RuntimeProtolizer.
+func ParseRuntime(name string) (Runtime, error) {
+	switch runtime := Runtime(name); runtime {
+	case "":
+		return RuntimeProtolizer, nil
+	case RuntimeProtolizer, RuntimeProtobufGo:
+		return runtime, nil
+	default:
+		return "", fmt.Errorf("%w: expected %s or %s, got %q", ErrInvalidRuntime, RuntimeProtolizer, RuntimeProtobufGo, name)
+	}
+}
+
+// compileProtobufGo generates the messages and enums of the file with
+// protoc-gen-go and appends the services and their options from the
+// protobuf-go template set.
+func compileProtobufGo(file *File) ([]byte, error) {
+	if err := checkProtobufVersion(); err != nil {
+		return nil, err
+	}
+	if err := checkProtobufGoOptions(file); err != nil {
+		return nil, err
+	}
+	plugin, err := protogen.Options{}.New(file.codeGeneratorRequest())
+	if err != nil {
+		return nil, err
+	}
+	plugin.SupportedFeatures = internal_gengo.SupportedFeatures
+	plugin.SupportedEditionsMinimum = internal_gengo.SupportedEditionsMinimum
+	plugin.SupportedEditionsMaximum = internal_gengo.SupportedEditionsMaximum
+
+	templates, err := parseTemplates(template.New("temp"), _protobufGoTemplates...)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range plugin.Files {
+		if !f.Generate {
+			continue
+		}
+		g := internal_gengo.GenerateFile(plugin, f)
+		data := *file
+		if len(file.Services) != 0 {
+			data.Services = protobufGoServices(g, f, file.Services)
+		}
+		out := bytes.NewBuffer([]byte{})
+		if err := templates.ExecuteTemplate(out, "ProtobufGo", &data); err != nil {
+			return nil, err
+		}
+		g.P(out.String())
+	}
+
+	response := plugin.Response()
+	if response.Error != nil {
+		return nil, errors.New(response.GetError())
+	}
+	if len(response.File) != 1 {
+		return nil, fmt.Errorf("expected one generated file for %s, got %d", file.Source, len(response.File))
+	}
+	return []byte(response.File[0].GetContent()), nil
+}
+
+// checkProtobufVersion rejects builds linked against another release of
+// google.golang.org/protobuf than _protocGenGoVersion, whose generator could
+// silently produce different code. Builds without module information are
+// trusted to follow go.mod.
+func checkProtobufVersion() error {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != _protobufModule {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		if dep.Version != _protocGenGoVersion {
+			return fmt.Errorf("%w: the %s runtime is generated with %s %s, got %s", ErrProtobufVersion, RuntimeProtobufGo, _protobufModule, _protocGenGoVersion, dep.Version)
+		}
+	}
+	return nil
+}
+
+// checkProtobufGoOptions rejects the options protoc-gen-go would ignore,
+// since the code it generates would not keep the promise made by the option.
+func checkProtobufGoOptions(file *File) error {
+	if file.Deterministic {
+		return fmt.Errorf("%w: %s is deterministic, which the %s runtime would ignore", ErrUnsupportedOption, file.Source, RuntimeProtobufGo)
+	}
+	for _, message := range file.Messages {
+		if message.Pooled {
+			return fmt.Errorf("%w: %s is pooled, which the %s runtime has no pools for", ErrUnsupportedOption, message.TypeName, RuntimeProtobufGo)
+		}
+		for _, field := range message.Fields {
+			switch {
+			case field.Encrypted:
+				return fmt.Errorf("%w: %s is encrypted, which the %s runtime would write in plaintext", ErrUnsupportedOption, field.FullName, RuntimeProtobufGo)
+			case field.Sensitive:
+				return fmt.Errorf("%w: %s is sensitive, which the %s runtime would print in clear", ErrUnsupportedOption, field.FullName, RuntimeProtobufGo)
+			case field.Rules != nil:
+				return fmt.Errorf("%w: %s has rules, which the %s runtime would not validate", ErrUnsupportedOption, field.FullName, RuntimeProtobufGo)
+			case field.GoType != nil:
+				return fmt.Errorf("%w: %s has a go_type, which the %s runtime would not convert to", ErrUnsupportedOption, field.FullName, RuntimeProtobufGo)
+			case len(field.Tags) != 0:
+				return fmt.Errorf("%w: %s has struct tags, which the %s runtime would not generate", ErrUnsupportedOption, field.FullName, RuntimeProtobufGo)
+			case field.Lazy:
+				return fmt.Errorf("%w: %s is lazy, which the %s runtime would decode eagerly", ErrUnsupportedOption, field.FullName, RuntimeProtobufGo)
+			}
+		}
+	}
+	for _, service := range file.Services {
+		if service.ValidateRequests {
+			return fmt.Errorf("%w: %s validates its requests, which the %s runtime has no validation for", ErrUnsupportedOption, service.Name, RuntimeProtobufGo)
+		}
+	}
+	return nil
+}
+
+// codeGeneratorRequest asks for the file to be generated, mapping it and
+// every file it imports to the Go package protov resolved for them. The
+// protov option definitions are left out since they only matter to the
+// compiler and have no Go package to import.
+func (file *File) codeGeneratorRequest() *pluginpb.CodeGeneratorRequest {
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.importPath},
+	}
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor, name string)
+	add = func(fd protoreflect.FileDescriptor, name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		fdp := protodesc.ToFileDescriptorProto(fd)
+		fdp.Name = proto.String(name)
+		fdp.Dependency = fdp.Dependency[:0]
+		fdp.PublicDependency, fdp.WeakDependency = nil, nil
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			imported := imports.Get(i)
+			if strings.HasPrefix(imported.Path(), _optionsImportPrefix) {
+				continue
+			}
+			if imported.IsPublic {
+				fdp.PublicDependency = append(fdp.PublicDependency, int32(len(fdp.Dependency)))
+			}
+			if imported.IsWeak {
+				fdp.WeakDependency = append(fdp.WeakDependency, int32(len(fdp.Dependency)))
+			}
+			fdp.Dependency = append(fdp.Dependency, imported.Path())
+			add(imported.FileDescriptor, imported.Path())
+		}
+		request.ProtoFile = append(request.ProtoFile, stableOptions(fdp))
+	}
+	add(file.descriptor, file.importPath)
+
+	params := make([]string, 0, len(file.goPackages))
+	for name, goPackage := range file.goPackages {
+		params = append(params, "M"+name+"="+goPackage)
+	}
+	sort.Strings(params)
+	request.Parameter = proto.String(strings.Join(params, ","))
+	return request
+}
+
+// stableOptions returns a copy of fdp whose options are decoded from a
+// deterministic encoding. The custom options parsed by the compiler are
+// dynamic messages, which protoc-gen-go would otherwise embed with their
+// fields in random order.
+func stableOptions(fdp *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fdp)
+	if err != nil {
+		return fdp
+	}
+	out := new(descriptorpb.FileDescriptorProto)
+	if err := proto.Unmarshal(data, out); err != nil {
+		return fdp
+	}
+	return out
+}
+
+// protobufGoServices returns copies of the services whose request and
+// response types are named as protoc-gen-go names them, qualified with their
+// package when they are imported. Messages generated by protoc-gen-go have no
+// pools, so the handlers allocate them. It also imports the packages used by
+// the service template.
+func protobufGoServices(g *protogen.GeneratedFile, f *protogen.File, services []*Service) []*Service {
+	g.QualifiedGoIdent(protogen.GoImportPath("context").Ident("Context"))
+	g.QualifiedGoIdent(protogen.GoImportPath("google.golang.org/protobuf/proto").Ident("Message"))
+
+	out := make([]*Service, len(services))
+	for i, service := range services {
+		copied := *service
+		copied.Rpcs = make([]*Rpc, len(service.Rpcs))
+		var methods []*protogen.Method
+		for _, s := range f.Services {
+			if string(s.Desc.Name()) == service.Name {
+				methods = s.Methods
+			}
+		}
+		for j, rpc := range service.Rpcs {
+			rpcCopy := *rpc
+			rpcCopy.PooledInput, rpcCopy.PooledOutput = false, false
+			for _, method := range methods {
+				if string(method.Desc.Name()) == rpc.Name {
+					rpcCopy.Input = g.QualifiedGoIdent(method.Input.GoIdent)
+					rpcCopy.Output = g.QualifiedGoIdent(method.Output.GoIdent)
+				}
+			}
+			copied.Rpcs[j] = &rpcCopy
+		}
+		out[i] = &copied
+	}
+	return out
+}
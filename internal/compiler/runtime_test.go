@@ -0,0 +1,149 @@
+package compiler
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+func TestParseRuntime(t *testing.T) {
+	tests := []struct {
+		name string
+		want Runtime
+		err  error
+	}{
+		{"", RuntimeProtolizer, nil},
+		{"protolizer", RuntimeProtolizer, nil},
+		{"protobuf-go", RuntimeProtobufGo, nil},
+		{"gogo", "", ErrInvalidRuntime},
+	}
+	for _, test := range tests {
+		got, err := ParseRuntime(test.name)
+		if !errors.Is(err, test.err) {
+			t.Errorf("ParseRuntime(%q) error = %v, want %v", test.name, err, test.err)
+		}
+		if got != test.want {
+			t.Errorf("ParseRuntime(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestProtocGenGoVersion(t *testing.T) {
+	data, err := os.ReadFile("../../go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, require := range mod.Require {
+		if require.Mod.Path == _protobufModule {
+			if require.Mod.Version != _protocGenGoVersion {
+				t.Errorf("go.mod requires %s %s, want %s", _protobufModule, require.Mod.Version, _protocGenGoVersion)
+			}
+			return
+		}
+	}
+	t.Errorf("go.mod does not require %s", _protobufModule)
+}
+
+func TestCheckProtobufVersion(t *testing.T) {
+	if err := checkProtobufVersion(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompileInvalidRuntime(t *testing.T) {
+	ast, err := Parse("testdata/conformance/scalars.proto", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Compile(ast.Files[0], "gogo"); !errors.Is(err, ErrInvalidRuntime) {
+		t.Errorf("Compile error = %v, want %v", err, ErrInvalidRuntime)
+	}
+}
+
+func TestCompileTestsProtobufGo(t *testing.T) {
+	ast, err := Parse("testdata/conformance/scalars.proto", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CompileTests(ast.Files[0], RuntimeProtobufGo); !errors.Is(err, ErrTestsUnsupported) {
+		t.Errorf("CompileTests error = %v, want %v", err, ErrTestsUnsupported)
+	}
+}
+
+func TestCompileProtobufGoUnsupportedOptions(t *testing.T) {
+	tests := map[string]struct {
+		source string
+		want   string
+	}{
+		"encrypted": {`message Outer { string email = 1 [(protov.encrypted) = true]; }`, "scratch.Outer.email is encrypted"},
+		"sensitive": {`message Outer { string email = 1 [(protov.sensitive) = true]; }`, "scratch.Outer.email is sensitive"},
+		"rules": {`import "protov/validate.proto";
+message Outer { string email = 1 [(protov.rules) = {required: true}]; }`, "scratch.Outer.email has rules"},
+		"go_type": {`message Outer { int64 timeout = 1 [(protov.go_type) = {name: "time.Duration", import: "time"}]; }`, "scratch.Outer.timeout has a go_type"},
+		"tags":    {`message Outer { string email = 1 [(protov.tags) = {key: "db", value: "mail"}]; }`, "scratch.Outer.email has struct tags"},
+		"default_tags": {`option (protov.default_tags) = {key: "db", naming: SNAKE};
+message Outer { string email = 1; }`, "scratch.Outer.email has struct tags"},
+		"lazy":   {`message Outer { Outer next = 1 [(protov.lazy) = true]; }`, "scratch.Outer.next is lazy"},
+		"pooled": {`message Outer { option (protov.pooled) = true; string email = 1; }`, "scratch.Outer is pooled"},
+		"pool_messages": {`option (protov.pool_messages) = true;
+message Outer { string email = 1; }`, "scratch.Outer is pooled"},
+		"deterministic": {`option (protov.deterministic) = true;
+message Outer { map<string, string> labels = 1; }`, "scratch.proto is deterministic"},
+		"validate_requests": {`import "protov/validate.proto";
+message Outer { string email = 1; }
+service Outers {
+    option (protov.validate_requests) = true;
+    rpc Get(Outer) returns (Outer);
+}`, "Outers validates its requests"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ast, err := parseSource(t, test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Compile(ast.Files[0], RuntimeProtobufGo)
+			if !errors.Is(err, ErrUnsupportedOption) || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Compile error = %v, want %v: %s", err, ErrUnsupportedOption, test.want)
+			}
+		})
+	}
+}
+
+func TestCodeGeneratorRequest(t *testing.T) {
+	ast, err := Parse("testdata/golden/service.proto", ImportMap{"google/protobuf/descriptor.proto": "example.com/descriptorpb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := ast.Files[0].codeGeneratorRequest()
+
+	names := make([]string, 0, len(request.ProtoFile))
+	for _, fdp := range request.ProtoFile {
+		names = append(names, fdp.GetName())
+		for _, dep := range fdp.GetDependency() {
+			if strings.HasPrefix(dep, _optionsImportPrefix) {
+				t.Errorf("%s still imports %s", fdp.GetName(), dep)
+			}
+		}
+	}
+	if want := []string{"google/protobuf/descriptor.proto", "service.proto"}; !slices.Equal(names, want) {
+		t.Errorf("files = %v, want %v", names, want)
+	}
+	if !slices.Equal(request.FileToGenerate, []string{"service.proto"}) {
+		t.Errorf("files to generate = %v", request.FileToGenerate)
+	}
+	params := strings.Split(request.GetParameter(), ",")
+	for _, want := range []string{"Mservice.proto=golden/gen;gen", "Mgoogle/protobuf/descriptor.proto=example.com/descriptorpb;descriptorpb"} {
+		if !slices.Contains(params, want) {
+			t.Errorf("parameters %v do not contain %s", params, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Namings of the default_tags file option
+const (
+	_protoNaming protoreflect.EnumNumber = iota
+	_snakeNaming
+	_camelNaming
+	_kebabNaming
+)
+
+// _reservedTags are the keys of the tags written by marshalTags.
+var _reservedTags = makeSet("protobuf", "protobuf_key", "protobuf_val", "protobuf_oneof", "json")
+
+// StructTag is a struct tag added to a generated field by the tags option or
+// the default_tags file option.
+type StructTag struct {
+	Key   string
+	Value string
+}
+
+func (t StructTag) String() string {
+	return t.Key + ":" + strconv.Quote(t.Value)
+}
+
+// name returns the name the tag gives to the field, which precedes its
+// options.
+func (t StructTag) name() string {
+	name, _, _ := strings.Cut(t.Value, ",")
+	return name
+}
+
+func (t StructTag) validate() error {
+	if _reservedTags[t.Key] {
+		return fmt.Errorf("tag %q is written by the generator", t.Key)
+	}
+	if t.Key == "" || strings.ContainsFunc(t.Key, func(r rune) bool {
+		return r <= ' ' || r == ':' || r == '"' || r == '`' || r == 0x7f
+	}) {
+		return fmt.Errorf("invalid tag key %q", t.Key)
+	}
+	if strings.Contains(t.Value, "`") {
+		return fmt.Errorf("the value of tag %q cannot contain a backquote", t.Key)
+	}
+	return nil
+}
+
+// defaultTag is an entry of the default_tags file option.
+type defaultTag struct {
+	key     string
+	naming  protoreflect.EnumNumber
+	options string
+}
+
+func parseDefaultTags(value any) ([]defaultTag, error) {
+	entries, _ := value.([]any)
+	out := make([]defaultTag, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		options, _ := entry.(map[string]any)
+		tag := defaultTag{}
+		tag.key, _ = options["Key"].(string)
+		tag.naming, _ = options["Naming"].(protoreflect.EnumNumber)
+		tag.options, _ = options["Options"].(string)
+		if err := (StructTag{Key: tag.key, Value: tag.options}).validate(); err != nil {
+			return nil, err
+		}
+		if seen[tag.key] {
+			return nil, fmt.Errorf("tag %q is set more than once", tag.key)
+		}
+		seen[tag.key] = true
+		out = append(out, tag)
+	}
+	return out, nil
+}
+
+func (t defaultTag) tag(fd protoreflect.FieldDescriptor) StructTag {
+	value := tagName(string(fd.Name()), t.naming)
+	if t.options != "" {
+		value += "," + t.options
+	}
+	return StructTag{Key: t.key, Value: value}
+}
+
+// structTags returns the tags added to the field: the default tags of the
+// file, replaced by the tags option of the field when it sets the same key,
+// followed by the other keys of the tags option.
+func (file *File) structTags(fd protoreflect.FieldDescriptor, options map[string]any) ([]StructTag, error) {
+	if fd.ContainingMessage().IsMapEntry() {
+		return nil, nil
+	}
+	entries, _ := options[_tagsOption].([]any)
+	tags := make([]StructTag, 0, len(entries))
+	overrides := make(map[string]StructTag, len(entries))
+	for _, entry := range entries {
+		options, _ := entry.(map[string]any)
+		tag := StructTag{}
+		tag.Key, _ = options["Key"].(string)
+		tag.Value, _ = options["Value"].(string)
+		if err := tag.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := overrides[tag.Key]; ok {
+			return nil, fmt.Errorf("tag %q is set more than once", tag.Key)
+		}
+		overrides[tag.Key] = tag
+		tags = append(tags, tag)
+	}
+
+	out := make([]StructTag, 0, len(file.defaultTags)+len(tags))
+	for _, def := range file.defaultTags {
+		if tag, ok := overrides[def.key]; ok {
+			out = append(out, tag)
+			continue
+		}
+		out = append(out, def.tag(fd))
+	}
+	for _, tag := range tags {
+		if !file.hasDefaultTag(tag.Key) {
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+func (file *File) hasDefaultTag(key string) bool {
+	for _, def := range file.defaultTags {
+		if def.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTagNames reports an error when two fields of a message are given the
+// same name by tags of the same key, which would map them to the same column
+// or key of the format the tag is read by.
+func checkTagNames(fields []*Field) error {
+	names := make(map[StructTag]string)
+	for _, field := range fields {
+		for _, tag := range field.Tags {
+			name := tag.name()
+			if name == "" || name == "-" {
+				continue
+			}
+			key := StructTag{Key: tag.Key, Value: name}
+			if other, ok := names[key]; ok {
+				return fmt.Errorf("fields %s and %s have the same %s tag %q", other, field.ProtoName, tag.Key, name)
+			}
+			names[key] = field.ProtoName
+		}
+	}
+	return nil
+}
+
+// tagName converts the name of a field to the given naming.
+func tagName(name string, naming protoreflect.EnumNumber) string {
+	words := splitWords(name)
+	switch naming {
+	case _snakeNaming:
+		return strings.Join(words, "_")
+	case _kebabNaming:
+		return strings.Join(words, "-")
+	case _camelNaming:
+		for i := 1; i < len(words); i++ {
+			runes := []rune(words[i])
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+		return strings.Join(words, "")
+	default:
+		return name
+	}
+}
+
+// splitWords splits a name into lower-cased words at underscores and at
+// changes of case, keeping runs of capitals such as "HTTP" together.
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			words = append(words, strings.ToLower(string(runes[start:end])))
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.':
+			flush(i)
+			start = i + 1
+		case i > start && unicode.IsUpper(r) && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			flush(i)
+			start = i
+		}
+	}
+	flush(len(runes))
+	return words
+}
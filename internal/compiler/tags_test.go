@@ -0,0 +1,85 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestTagName(t *testing.T) {
+	tests := []struct {
+		name   string
+		naming protoreflect.EnumNumber
+		want   string
+	}{
+		{"user_id", _snakeNaming, "user_id"},
+		{"userID", _snakeNaming, "user_id"},
+		{"HTTPServer", _snakeNaming, "http_server"},
+		{"field2Name", _snakeNaming, "field2_name"},
+		{"created_at", _camelNaming, "createdAt"},
+		{"HTTPServer", _camelNaming, "httpServer"},
+		{"created_at", _kebabNaming, "created-at"},
+		{"createdAt", _protoNaming, "createdAt"},
+	}
+	for _, test := range tests {
+		if got := tagName(test.name, test.naming); got != test.want {
+			t.Errorf("tagName(%q, %d) = %q, want %q", test.name, test.naming, got, test.want)
+		}
+	}
+}
+
+func TestStructTagErrors(t *testing.T) {
+	tests := map[string]struct {
+		source string
+		err    string
+	}{
+		"reserved key": {
+			source: `message Setting { string name = 1 [(protov.tags) = {key: "json", value: "n"}]; }`,
+			err:    "written by the generator",
+		},
+		"invalid key": {
+			source: `message Setting { string name = 1 [(protov.tags) = {key: "my tag", value: "n"}]; }`,
+			err:    "invalid tag key",
+		},
+		"backquote": {
+			source: "message Setting { string name = 1 [(protov.tags) = {key: \"db\", value: \"`n`\"}]; }",
+			err:    "cannot contain a backquote",
+		},
+		"duplicate key": {
+			source: `message Setting {
+    string name = 1 [(protov.tags) = {key: "db", value: "a"}, (protov.tags) = {key: "db", value: "b"}];
+}`,
+			err: `tag "db" is set more than once`,
+		},
+		"duplicate default": {
+			source: `option (protov.default_tags) = {key: "db"};
+option (protov.default_tags) = {key: "db", naming: SNAKE};
+message Setting { string name = 1; }`,
+			err: `tag "db" is set more than once`,
+		},
+		"name collision": {
+			source: `option (protov.default_tags) = {key: "db", naming: SNAKE};
+message Setting {
+    string user_id = 1;
+    string userID = 2;
+}`,
+			err: `fields user_id and userID have the same db tag "user_id"`,
+		},
+		"override collision": {
+			source: `message Setting {
+    string name = 1 [(protov.tags) = {key: "db", value: "label,omitempty"}];
+    string label = 2 [(protov.tags) = {key: "db", value: "label"}];
+}`,
+			err: `fields name and label have the same db tag "label"`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseSource(t, test.source)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("Parse() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package gen
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/vedadiyan/protolizer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// benchmarkInput returns the encoding of a random message of md, populated
+// to the same depth as the conformance inputs.
+func benchmarkInput(b *testing.B, md protoreflect.MessageDescriptor) []byte {
+	r := rand.New(rand.NewSource(seed(b)))
+	data, err := proto.Marshal(randomMessage(r, md, 3))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+// BenchmarkMarshal compares encoding through the protolizer codec with the
+// generated MarshalAppend writing into a buffer sized by Size.
+func BenchmarkMarshal(b *testing.B) {
+	messages := descriptors(b)
+	for _, name := range names() {
+		m := _messages[name]()
+		if err := m.Unmarshal(benchmarkInput(b, messages[name])); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name+"/protolizer", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(m.Size()))
+			for i := 0; i < b.N; i++ {
+				if _, err := m.Marshal(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(name+"/MarshalAppend", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(m.Size()))
+			for i := 0; i < b.N; i++ {
+				if _, err := m.MarshalAppend(make([]byte, 0, m.Size())); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkUnmarshal compares decoding through the protolizer codec with the
+// generated Unmarshal.
+func BenchmarkUnmarshal(b *testing.B) {
+	messages := descriptors(b)
+	for _, name := range names() {
+		data := benchmarkInput(b, messages[name])
+		b.Run(name+"/protolizer", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if err := protolizer.StaticCodec().Unmarshal(data, _messages[name]()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(name+"/Unmarshal", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if err := _messages[name]().Unmarshal(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
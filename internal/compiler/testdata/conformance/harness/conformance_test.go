@@ -0,0 +1,193 @@
+package gen
+
+import (
+	_ "embed"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/vedadiyan/protolizer"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type generated interface {
+	Marshal() ([]byte, error)
+	Size() int
+	MarshalAppend(b []byte) ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+//go:embed descriptor.binpb
+var _descriptorSet []byte
+
+func descriptors(tb testing.TB) map[string]protoreflect.MessageDescriptor {
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(_descriptorSet, set); err != nil {
+		tb.Fatal(err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	out := make(map[string]protoreflect.MessageDescriptor, len(_messages))
+	for name := range _messages {
+		descriptor, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		out[name] = descriptor.(protoreflect.MessageDescriptor)
+	}
+	return out
+}
+
+func names() []string {
+	out := make([]string, 0, len(_messages))
+	for name := range _messages {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func seed(tb testing.TB) int64 {
+	value := os.Getenv("PROTOV_CONFORMANCE_SEED")
+	if value == "" {
+		return 1
+	}
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return seed
+}
+
+// forEachInput calls check with random messages of every type in the corpus
+// encoded by protobuf-go, a quarter of them followed by an unknown field, and
+// with the message protobuf-go decodes from them.
+func forEachInput(t *testing.T, check func(t *testing.T, md protoreflect.MessageDescriptor, data []byte, want *dynamicpb.Message)) {
+	messages := descriptors(t)
+	for _, name := range names() {
+		t.Run(name, func(t *testing.T) {
+			md := messages[name]
+			r := rand.New(rand.NewSource(seed(t)))
+			for i := 0; i < 200; i++ {
+				data, err := proto.MarshalOptions{Deterministic: true}.Marshal(randomMessage(r, md, 3))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i%4 == 0 {
+					data = protowire.AppendTag(data, protowire.MaxValidNumber, protowire.VarintType)
+					data = protowire.AppendVarint(data, r.Uint64())
+				}
+				want := dynamicpb.NewMessage(md)
+				if err := proto.Unmarshal(data, want); err != nil {
+					t.Fatal(err)
+				}
+				check(t, md, data, want)
+			}
+		})
+	}
+}
+
+// expectEncoding fails the test unless protobuf-go decodes out to want.
+func expectEncoding(t *testing.T, md protoreflect.MessageDescriptor, out []byte, want *dynamicpb.Message) {
+	t.Helper()
+	got := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(out, got); err != nil {
+		t.Fatalf("reference decode failed: %v\noutput: %x\nmessage: %s", err, out, prototext.Format(want))
+	}
+	if !proto.Equal(want, got) {
+		t.Fatalf("round trip mismatch\nwant: %s\ngot:  %s", prototext.Format(want), prototext.Format(got))
+	}
+}
+
+// TestConformance feeds random messages encoded by protobuf-go through the
+// generated decoder and encoder and expects protobuf-go to read back the same
+// message, unknown fields included.
+func TestConformance(t *testing.T) {
+	forEachInput(t, func(t *testing.T, md protoreflect.MessageDescriptor, data []byte, want *dynamicpb.Message) {
+		m := _messages[string(md.FullName())]()
+		if err := protolizer.StaticCodec().Unmarshal(data, m); err != nil {
+			t.Fatalf("decode failed: %v\ninput: %x\nmessage: %s", err, data, prototext.Format(want))
+		}
+		out, err := m.Marshal()
+		if err != nil {
+			t.Fatalf("encode failed: %v\nmessage: %s", err, prototext.Format(want))
+		}
+		expectEncoding(t, md, out, want)
+	})
+}
+
+// TestWireMethods runs the same checks against the generated Unmarshal and
+// MarshalAppend, and expects Size to predict the length of the encoding.
+func TestWireMethods(t *testing.T) {
+	forEachInput(t, func(t *testing.T, md protoreflect.MessageDescriptor, data []byte, want *dynamicpb.Message) {
+		m := _messages[string(md.FullName())]()
+		if err := m.Unmarshal(data); err != nil {
+			t.Fatalf("decode failed: %v\ninput: %x\nmessage: %s", err, data, prototext.Format(want))
+		}
+		out, err := m.MarshalAppend(nil)
+		if err != nil {
+			t.Fatalf("encode failed: %v\nmessage: %s", err, prototext.Format(want))
+		}
+		if size := m.Size(); size != len(out) {
+			t.Fatalf("Size returned %d, MarshalAppend wrote %d bytes\nmessage: %s", size, len(out), prototext.Format(want))
+		}
+		expectEncoding(t, md, out, want)
+	})
+}
+
+// FuzzDecode checks that the generated decoders reject malformed input with
+// an error rather than a panic, and that whatever they accept can be encoded
+// and decoded again.
+func FuzzDecode(f *testing.F) {
+	messages := descriptors(f)
+	list := names()
+	r := rand.New(rand.NewSource(seed(f)))
+	for i, name := range list {
+		for j := 0; j < 4; j++ {
+			data, err := proto.Marshal(randomMessage(r, messages[name], 2))
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(uint8(i), data)
+			if len(data) > 1 {
+				f.Add(uint8(i), data[:len(data)/2])
+			}
+		}
+	}
+	f.Add(uint8(0), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01})
+	f.Add(uint8(0), []byte{0x0a, 0x7f})
+
+	f.Fuzz(func(t *testing.T, index uint8, data []byte) {
+		name := list[int(index)%len(list)]
+		if fast := _messages[name](); fast.Unmarshal(data) == nil {
+			out, err := fast.MarshalAppend(nil)
+			if err != nil {
+				t.Fatalf("encoding a decoded %s failed: %v", name, err)
+			}
+			if err := _messages[name]().Unmarshal(out); err != nil {
+				t.Fatalf("decoding a re-encoded %s failed: %v\ninput: %x\noutput: %x", name, err, data, out)
+			}
+		}
+		m := _messages[name]()
+		if err := protolizer.StaticCodec().Unmarshal(data, m); err != nil {
+			return
+		}
+		out, err := m.Marshal()
+		if err != nil {
+			t.Fatalf("encoding a decoded %s failed: %v", name, err)
+		}
+		if err := protolizer.StaticCodec().Unmarshal(out, _messages[name]()); err != nil {
+			t.Fatalf("decoding a re-encoded %s failed: %v\ninput: %x\noutput: %x", name, err, data, out)
+		}
+	})
+}
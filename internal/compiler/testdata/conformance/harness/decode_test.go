@@ -0,0 +1,192 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/vedadiyan/protolizer/metadata"
+	"github.com/vedadiyan/protolizer/pdk"
+	"github.com/vedadiyan/protov/pkg/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type decoder interface {
+	Decode(field *metadata.Field, buffer *bytes.Buffer) error
+}
+
+// decodeFields decodes data into m the way the protolizer codec does: it
+// reads each tag with the codec's tag decoding and hands the field number
+// and wire type of that tag to Decode.
+func decodeFields(t *testing.T, m decoder, data []byte) {
+	t.Helper()
+	buffer := bytes.NewBuffer(data)
+	for buffer.Len() != 0 {
+		num, wireType, read, err := pdk.TagPeek(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read()
+		field := new(metadata.Field)
+		setFieldNum(&field.Tags.Protobuf.FieldNum, num)
+		field.Tags.Protobuf.WireType = wireType
+		if err := m.Decode(field, buffer); err != nil {
+			t.Fatalf("Decode of field %d failed: %v\ninput: %x", num, err, data)
+		}
+	}
+}
+
+func setFieldNum[T ~int | ~int32 | ~int64](dst *T, num int32) {
+	*dst = T(num)
+}
+
+// TestDecodeUnknown checks that the fields Decode does not know are kept
+// with the wire type of their tag, whatever that type is.
+func TestDecodeUnknown(t *testing.T) {
+	var data []byte
+	data = protowire.AppendTag(data, 100, protowire.VarintType)
+	data = protowire.AppendVarint(data, 150)
+	data = protowire.AppendTag(data, 101, protowire.Fixed64Type)
+	data = protowire.AppendFixed64(data, 7)
+	data = protowire.AppendTag(data, 102, protowire.BytesType)
+	data = protowire.AppendBytes(data, []byte("abc"))
+	data = protowire.AppendTag(data, 103, protowire.StartGroupType)
+	data = protowire.AppendTag(data, 1, protowire.VarintType)
+	data = protowire.AppendVarint(data, 1)
+	data = protowire.AppendTag(data, 103, protowire.EndGroupType)
+	data = protowire.AppendTag(data, 104, protowire.Fixed32Type)
+	data = protowire.AppendFixed32(data, 9)
+
+	m := new(Item)
+	decodeFields(t, m, data)
+	out, err := m.MarshalAppend(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatalf("unknown fields were not kept\nwant: %x\ngot:  %x", data, out)
+	}
+}
+
+// TestUnknownBehindZeroField checks that the unknown fields of a message
+// whose highest numbered fields are not set are encoded once, at the top
+// level and nested.
+func TestUnknownBehindZeroField(t *testing.T) {
+	unknown := protowire.AppendTag(nil, 9, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 1)
+
+	var envelope []byte
+	envelope = protowire.AppendTag(envelope, 1, protowire.BytesType)
+	envelope = protowire.AppendString(envelope, "id")
+	envelope = append(envelope, unknown...)
+
+	var child []byte
+	child = protowire.AppendTag(child, 1, protowire.BytesType)
+	child = protowire.AppendString(child, "inner")
+	child = append(child, unknown...)
+	var item []byte
+	item = protowire.AppendTag(item, 1, protowire.BytesType)
+	item = protowire.AppendString(item, "outer")
+	item = protowire.AppendTag(item, 3, protowire.BytesType)
+	item = protowire.AppendBytes(item, child)
+
+	for _, tc := range []struct {
+		name string
+		m    interface {
+			Unmarshal([]byte) error
+			Marshal() ([]byte, error)
+			MarshalAppend([]byte) ([]byte, error)
+		}
+		data []byte
+	}{
+		{"top level", new(Envelope), envelope},
+		{"nested", new(Item), item},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.m.Unmarshal(tc.data); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := tc.m.Marshal(); err != nil || !bytes.Equal(got, tc.data) {
+				t.Fatalf("Marshal = %x, %v, want %x", got, err, tc.data)
+			}
+			if got, err := tc.m.MarshalAppend(nil); err != nil || !bytes.Equal(got, tc.data) {
+				t.Fatalf("MarshalAppend = %x, %v, want %x", got, err, tc.data)
+			}
+		})
+	}
+}
+
+// TestDecodeRepeatedZigZag checks that Decode reads every occurrence of a
+// repeated sint32 or sint64 field by the wire type of its tag, packed or
+// not, including the first one.
+func TestDecodeRepeatedZigZag(t *testing.T) {
+	packed := func(values ...int64) []byte {
+		var out []byte
+		for _, v := range values {
+			out = protowire.AppendVarint(out, protowire.EncodeZigZag(v))
+		}
+		return out
+	}
+	var data []byte
+	data = protowire.AppendTag(data, 7, protowire.BytesType)
+	data = protowire.AppendBytes(data, packed(-1, 2))
+	data = protowire.AppendTag(data, 7, protowire.VarintType)
+	data = protowire.AppendVarint(data, protowire.EncodeZigZag(-3))
+	data = protowire.AppendTag(data, 8, protowire.VarintType)
+	data = protowire.AppendVarint(data, protowire.EncodeZigZag(-4))
+	data = protowire.AppendTag(data, 8, protowire.BytesType)
+	data = protowire.AppendBytes(data, packed(5, -6))
+
+	m := new(Repeated)
+	decodeFields(t, m, data)
+	want := &Repeated{Sint32s: []int{-1, 2, -3}, Sint64s: []int64{-4, 5, -6}}
+	if !m.Equal(want) {
+		t.Fatalf("Decode = %v, want %v", m, want)
+	}
+}
+
+// TestUnmarshalInvalidUTF8 feeds invalid UTF-8 to every string field, map
+// keys and values included, and expects the generated Unmarshal to reject it
+// exactly when protobuf-go does, which is for proto3 strings.
+func TestUnmarshalInvalidUTF8(t *testing.T) {
+	messages := descriptors(t)
+	rejected := 0
+	for _, name := range names() {
+		md := messages[name]
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			var value []byte
+			switch {
+			case fd.IsMap() && fd.MapKey().Kind() == protoreflect.StringKind:
+				value = protowire.AppendTag(value, 1, protowire.BytesType)
+				value = protowire.AppendString(value, "\xff")
+			case fd.IsMap() && fd.MapValue().Kind() == protoreflect.StringKind:
+				value = protowire.AppendTag(value, 2, protowire.BytesType)
+				value = protowire.AppendString(value, "\xff")
+			case !fd.IsMap() && fd.Kind() == protoreflect.StringKind:
+				value = []byte("\xff")
+			default:
+				continue
+			}
+			data := protowire.AppendTag(nil, fd.Number(), protowire.BytesType)
+			data = protowire.AppendBytes(data, value)
+			want := proto.Unmarshal(data, dynamicpb.NewMessage(md))
+			err := _messages[name]().Unmarshal(data)
+			switch {
+			case want == nil && err != nil:
+				t.Errorf("%s: unexpected error: %v", fd.FullName(), err)
+			case want != nil && !errors.Is(err, wire.ErrInvalidUTF8):
+				t.Errorf("%s: expected wire.ErrInvalidUTF8, got %v", fd.FullName(), err)
+			case want != nil:
+				rejected++
+			}
+		}
+	}
+	if rejected == 0 {
+		t.Fatal("the corpus has no proto3 string field")
+	}
+}
@@ -0,0 +1,71 @@
+package gen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/vedadiyan/protov/pkg/encryption"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// TestEncryptedFields checks that the encrypted fields are written as
+// ciphertexts, which protobuf-go reads as plain string and bytes fields, and
+// that they decode back to their plaintext. The cipher is registered by the
+// generated tests.
+func TestEncryptedFields(t *testing.T) {
+	x := &Sealed{Email: "ada@example.com", Key: []byte("k3y"), Label: "primary"}
+	data, err := x.MarshalAppend(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := x.Size(); size != len(data) {
+		t.Fatalf("Size returned %d, MarshalAppend wrote %d bytes", size, len(data))
+	}
+	if bytes.Contains(data, []byte("ada@example.com")) || bytes.Contains(data, []byte("k3y")) {
+		t.Fatalf("the encoding %x holds a plaintext", data)
+	}
+
+	dynamic := dynamicpb.NewMessage(x.ProtoReflect().Descriptor())
+	if err := proto.Unmarshal(data, dynamic); err != nil {
+		t.Fatal(err)
+	}
+	fields := dynamic.Descriptor().Fields()
+	if label := dynamic.Get(fields.ByName("label")).String(); label != "primary" {
+		t.Fatalf("label = %q", label)
+	}
+	ciphertext := dynamic.Get(fields.ByName("email")).String()
+	if plaintext, err := encryption.DecryptString("conformance.Sealed.email", ciphertext); err != nil || plaintext != x.Email {
+		t.Fatalf("DecryptString = %q, %v", plaintext, err)
+	}
+	if _, err := encryption.DecryptString("conformance.Sealed.label", ciphertext); err == nil {
+		t.Fatal("the ciphertext of email was accepted by label")
+	}
+
+	decoded := new(Sealed)
+	if err := decoded.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(x) {
+		t.Fatalf("Unmarshal = %+v, want %+v", decoded, x)
+	}
+
+	text, err := x.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(text, []byte("ada@example.com")) {
+		t.Fatalf("the JSON form %s holds a plaintext", text)
+	}
+	decoded.Reset()
+	if err := decoded.UnmarshalJSON(text); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(x) {
+		t.Fatalf("UnmarshalJSON(%s) = %+v, want %+v", text, decoded, x)
+	}
+	if s := x.String(); strings.Contains(s, "ada@example.com") || !strings.Contains(s, "primary") {
+		t.Fatalf("String = %s", s)
+	}
+}
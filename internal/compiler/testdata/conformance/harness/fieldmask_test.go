@@ -0,0 +1,85 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/vedadiyan/protov/pkg/validation"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func mask(paths ...string) *fieldmaskpb.FieldMask {
+	return &fieldmaskpb.FieldMask{Paths: paths}
+}
+
+func TestValidateFieldMask(t *testing.T) {
+	valid := mask(EnvelopePathId, "payload.child.name", "header", "header.count")
+	if err := new(Envelope).ValidateFieldMask(valid); err != nil {
+		t.Fatalf("ValidateFieldMask(%v) = %v", valid.Paths, err)
+	}
+	invalid := mask("id.length", "payload.child.missing", "", "missing", "payload.")
+	err := new(Envelope).ValidateFieldMask(invalid)
+	errs, ok := err.(validation.Errors)
+	if !ok {
+		t.Fatalf("ValidateFieldMask(%v) = %v, want validation.Errors", invalid.Paths, err)
+	}
+	// An empty nested path is reported on the field it would descend into.
+	want := []string{"id.length", "payload.child.missing", "", "missing", "payload"}
+	if len(errs) != len(want) {
+		t.Fatalf("ValidateFieldMask(%v) = %v, want errors for %q", invalid.Paths, err, want)
+	}
+	for i, path := range want {
+		if errs[i].Path != path {
+			t.Errorf("error %d is for %q, want %q", i, errs[i].Path, path)
+		}
+	}
+}
+
+func TestMergeFieldMask(t *testing.T) {
+	dst := &Envelope{
+		Id:      "dst",
+		Payload: &Item{Name: "dst", Count: 1, Child: &Item{Name: "dst child", Count: 2}},
+		Header:  &Item{Name: "dst header"},
+	}
+	src := &Envelope{
+		Id:      "src",
+		Payload: &Item{Name: "src", Count: 3, Child: &Item{Name: "src child", Count: 4}},
+	}
+	if err := dst.MergeFieldMask(src, mask("payload.child.count", "header", "payload.name")); err != nil {
+		t.Fatal(err)
+	}
+	want := &Envelope{
+		Id:      "dst",
+		Payload: &Item{Name: "src", Count: 1, Child: &Item{Name: "dst child", Count: 4}},
+	}
+	if !dst.Equal(want) {
+		t.Fatalf("MergeFieldMask produced %+v, want %+v", dst, want)
+	}
+	if src.Payload.Child == dst.Payload.Child {
+		t.Fatal("MergeFieldMask shares a nested message with the source")
+	}
+	if err := dst.MergeFieldMask(src, mask("missing")); err == nil {
+		t.Fatal("MergeFieldMask accepted an invalid mask")
+	}
+	if !dst.Equal(want) {
+		t.Fatal("MergeFieldMask changed the message for an invalid mask")
+	}
+}
+
+func TestFilterFieldMask(t *testing.T) {
+	x := &Envelope{
+		Id:      "id",
+		Payload: &Item{Name: "payload", Count: 1, Child: &Item{Name: "child", Count: 2}},
+		Header:  &Item{Name: "header"},
+	}
+	x.SetUnknownFields([]byte{0x78, 0x01})
+	if err := x.FilterFieldMask(mask("payload.child.name", "payload.count", "id")); err != nil {
+		t.Fatal(err)
+	}
+	want := &Envelope{
+		Id:      "id",
+		Payload: &Item{Count: 1, Child: &Item{Name: "child"}},
+	}
+	if !x.Equal(want) {
+		t.Fatalf("FilterFieldMask produced %+v, want %+v", x, want)
+	}
+}
@@ -0,0 +1,96 @@
+package gen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vedadiyan/protolizer"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// TestGoTypeRoundTrip checks that fields mapped to Go types keep their value
+// through the wire and JSON encodings.
+func TestGoTypeRoundTrip(t *testing.T) {
+	amount, _ := MoneyFromCents(-1250)
+	code, _ := ParseCode("EUR")
+	x := &Mapped{
+		Amount:   amount,
+		Code:     code,
+		Timeout:  3 * time.Second,
+		Document: json.RawMessage(`{"a":1}`),
+		Flags:    Flags(5),
+		Ratio:    Ratio(0.5),
+		Active:   Switch(true),
+	}
+	data, err := x.MarshalAppend(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(Mapped)
+	if err := decoded.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(x) {
+		t.Fatalf("Unmarshal = %+v, want %+v", decoded, x)
+	}
+	if decoded.GetAmount().Cents() != -1250 || decoded.GetCode().String() != "EUR" {
+		t.Fatalf("the converted fields were decoded as %+v", decoded)
+	}
+
+	text, err := x.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded.Reset()
+	if err := decoded.UnmarshalJSON(text); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(x) {
+		t.Fatalf("UnmarshalJSON(%s) = %+v, want %+v", text, decoded, x)
+	}
+}
+
+// TestGoTypeRejected checks that the errors of a converter are reported with
+// the name of the field.
+func TestGoTypeRejected(t *testing.T) {
+	data := protowire.AppendTag(nil, 2, protowire.BytesType)
+	data = protowire.AppendString(data, "bad!")
+	if err := new(Mapped).Unmarshal(data); err == nil || !strings.HasPrefix(err.Error(), "code: ") {
+		t.Fatalf("Unmarshal = %v, want an error for code", err)
+	}
+	if err := new(Mapped).UnmarshalJSON([]byte(`{"code":"bad!"}`)); err == nil || !strings.Contains(err.Error(), "code: ") {
+		t.Fatalf("UnmarshalJSON = %v, want an error for code", err)
+	}
+}
+
+// TestGoTypeZero checks that a converted field holding the zero value of its
+// Go type is unset, even though that value converts to a non-empty string.
+func TestGoTypeZero(t *testing.T) {
+	x := new(Mapped)
+	if size := x.Size(); size != 0 {
+		t.Fatalf("Size = %d, want 0", size)
+	}
+	if data, err := x.MarshalAppend(nil); err != nil || len(data) != 0 {
+		t.Fatalf("MarshalAppend = %x, %v, want nothing", data, err)
+	}
+	if data, err := protolizer.StaticCodec().Marshal(x); err != nil || len(data) != 0 {
+		t.Fatalf("the codec encoded %x, %v, want nothing", data, err)
+	}
+	if text, err := x.MarshalJSON(); err != nil || strings.Contains(string(text), "ident") {
+		t.Fatalf("MarshalJSON = %s, %v, want no ident", text, err)
+	}
+	merged := &Mapped{Ident: Ident{"kept"}}
+	merged.Merge(x)
+	if merged.Ident.String() != "kept" {
+		t.Fatalf("Merge overwrote ident with %s", merged.Ident)
+	}
+
+	if err := new(Ticket).Validate(); err == nil || !strings.Contains(err.Error(), "ident") {
+		t.Fatalf("Validate = %v, want ident to be required", err)
+	}
+	if err := (&Ticket{Ident: Ident{"set"}}).Validate(); err != nil {
+		t.Fatalf("Validate = %v, want no error", err)
+	}
+}
@@ -0,0 +1,144 @@
+package gen
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// TestLazyPassThrough checks that a lazy field that is never stored is encoded
+// again from the bytes it was decoded from, even when they are not in the
+// canonical field order, and that reading it decodes those bytes.
+func TestLazyPassThrough(t *testing.T) {
+	var payload []byte
+	payload = protowire.AppendTag(payload, 2, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 7)
+	payload = protowire.AppendTag(payload, 1, protowire.BytesType)
+	payload = protowire.AppendString(payload, "name")
+	var data []byte
+	data = protowire.AppendTag(data, 2, protowire.BytesType)
+	data = protowire.AppendBytes(data, payload)
+
+	x := new(Envelope)
+	if err := x.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if !x.HasPayload() {
+		t.Fatal("the lazy field is not reported as set")
+	}
+	if got, err := x.MarshalAppend(nil); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("MarshalAppend = %x, %v, want the decoded bytes %x", got, err, data)
+	}
+	if got, err := x.Marshal(); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Marshal = %x, %v, want the decoded bytes %x", got, err, data)
+	}
+	if clone := x.Clone(); !clone.Equal(x) {
+		t.Fatal("the clone of an unread lazy field differs")
+	}
+
+	payloadField := x.GetPayload()
+	if payloadField.GetName() != "name" || payloadField.GetCount() != 7 {
+		t.Fatalf("GetPayload decoded %+v", payloadField)
+	}
+	if got, err := x.MarshalAppend(nil); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("MarshalAppend after reading the field = %x, %v, want the decoded bytes %x", got, err, data)
+	}
+
+	x.SetPayload(payloadField)
+	var canonical []byte
+	canonical = protowire.AppendTag(canonical, 1, protowire.BytesType)
+	canonical = protowire.AppendString(canonical, "name")
+	canonical = protowire.AppendTag(canonical, 2, protowire.VarintType)
+	canonical = protowire.AppendVarint(canonical, 7)
+	want := protowire.AppendTag(nil, 2, protowire.BytesType)
+	want = protowire.AppendBytes(want, canonical)
+	if got, err := x.MarshalAppend(nil); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("MarshalAppend after storing the field = %x, %v, want %x", got, err, want)
+	}
+}
+
+func TestLazyMalformed(t *testing.T) {
+	data := protowire.AppendTag(nil, 2, protowire.BytesType)
+	data = protowire.AppendBytes(data, []byte{0x0a, 0x05, 'a'})
+
+	x := new(Envelope)
+	if err := x.Unmarshal(data); err != nil {
+		t.Fatalf("decoding a malformed lazy field failed eagerly: %v", err)
+	}
+	if x.GetPayload() != nil {
+		t.Fatal("GetPayload returned a message for a malformed encoding")
+	}
+	if err := x.Validate(); err == nil {
+		t.Fatal("Validate did not report the malformed lazy field")
+	}
+	if got, err := x.MarshalAppend(nil); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("MarshalAppend = %x, %v, want the decoded bytes %x", got, err, data)
+	}
+}
+
+// TestLazyReadOnly checks that the getter, Equal, Merge and MergeFieldMask
+// leave the lazy fields they read undecoded, which the bytes encoded again
+// from them would show, so that they are safe to call alongside other reads.
+func TestLazyReadOnly(t *testing.T) {
+	var payload []byte
+	payload = protowire.AppendTag(payload, 2, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 7)
+	payload = protowire.AppendTag(payload, 1, protowire.BytesType)
+	payload = protowire.AppendString(payload, "name")
+	data := protowire.AppendTag(nil, 2, protowire.BytesType)
+	data = protowire.AppendBytes(data, payload)
+
+	decode := func() *Envelope {
+		x := new(Envelope)
+		if err := x.Unmarshal(data); err != nil {
+			t.Fatal(err)
+		}
+		return x
+	}
+	unchanged := func(name string, x *Envelope) {
+		t.Helper()
+		if got, err := x.MarshalAppend(nil); err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("%s was decoded: MarshalAppend = %x, %v, want %x", name, got, err, data)
+		}
+	}
+
+	a, b := decode(), decode()
+	if p := a.GetPayload(); p.GetName() != "name" || p.GetCount() != 7 {
+		t.Fatalf("GetPayload decoded %+v", p)
+	}
+	unchanged("the receiver of GetPayload", a)
+	if !a.Equal(b) {
+		t.Fatal("identical lazy fields differ")
+	}
+	unchanged("the receiver of Equal", a)
+	unchanged("the argument of Equal", b)
+
+	decoded := decode()
+	decoded.SetPayload(decoded.GetPayload())
+	if !decoded.Equal(a) || !a.Equal(decoded) {
+		t.Fatal("a decoded lazy field differs from its encoding")
+	}
+	unchanged("the undecoded side of Equal", a)
+
+	dst := new(Envelope)
+	dst.Merge(a)
+	unchanged("the source of Merge", a)
+	if !dst.Equal(a) {
+		t.Fatal("Merge into an empty message lost the lazy field")
+	}
+	decoded.Merge(a)
+	unchanged("the source of Merge into a decoded field", a)
+	if p := decoded.GetPayload(); p.GetName() != "name" || p.GetCount() != 7 {
+		t.Fatalf("Merge produced %+v", p)
+	}
+
+	masked := new(Envelope)
+	if err := masked.MergeFieldMask(a, mask("payload.name")); err != nil {
+		t.Fatal(err)
+	}
+	unchanged("the source of MergeFieldMask", a)
+	if p := masked.GetPayload(); p.GetName() != "name" || p.GetCount() != 0 {
+		t.Fatalf("MergeFieldMask produced %+v", p)
+	}
+}
@@ -0,0 +1,64 @@
+package gen
+
+import (
+	"errors"
+	"strings"
+)
+
+// Money is mapped to an int64 amount of cents through converters.
+type Money struct {
+	cents int64
+}
+
+func MoneyFromCents(cents int64) (Money, error) {
+	return Money{cents}, nil
+}
+
+func (m Money) Cents() int64 {
+	return m.cents
+}
+
+// Code is mapped to a string through a converter that rejects the strings
+// containing "!", which the random messages of the harness never do.
+type Code struct {
+	value string
+}
+
+func ParseCode(value string) (Code, error) {
+	if strings.Contains(value, "!") {
+		return Code{}, errors.New("invalid code")
+	}
+	return Code{value}, nil
+}
+
+func (c Code) String() string {
+	return c.value
+}
+
+// Ident is mapped to a string through converters that write its zero value
+// as "-", the way the zero uuid.UUID is written as a string of zeros. The
+// random strings of the harness never are "-".
+type Ident struct {
+	value string
+}
+
+func ParseIdent(value string) (Ident, error) {
+	if value == "-" {
+		return Ident{}, nil
+	}
+	return Ident{value}, nil
+}
+
+func (i Ident) String() string {
+	if i.value == "" {
+		return "-"
+	}
+	return i.value
+}
+
+// Flags, Ratio and Switch are mapped through Go conversions.
+type (
+	Flags  int
+	Ratio  float32
+	Switch bool
+)
@@ -0,0 +1,95 @@
+package gen
+
+import (
+	"math"
+	"math/rand"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// randomMessage populates a random subset of the fields of md, recursing into
+// nested messages until depth is exhausted.
+func randomMessage(r *rand.Rand, md protoreflect.MessageDescriptor, depth int) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if r.Intn(3) == 0 {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			entries := m.Mutable(fd).Map()
+			for n := r.Intn(4); n > 0; n-- {
+				key := randomValue(r, fd.MapKey(), depth)
+				entries.Set(key.MapKey(), randomValue(r, fd.MapValue(), depth))
+			}
+		case fd.IsList():
+			items := m.Mutable(fd).List()
+			for n := r.Intn(4); n > 0; n-- {
+				items.Append(randomValue(r, fd, depth))
+			}
+		default:
+			m.Set(fd, randomValue(r, fd, depth))
+		}
+	}
+	return m
+}
+
+func randomValue(r *rand.Rand, fd protoreflect.FieldDescriptor, depth int) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(r.Intn(2) == 1)
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		return protoreflect.ValueOfEnum(values.Get(r.Intn(values.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(randomBits(r)))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(int64(randomBits(r)))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(randomBits(r)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(randomBits(r))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(randomFloat(r)))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(randomFloat(r))
+	case protoreflect.StringKind:
+		alphabet := []rune("aZ09_ é€😀")
+		runes := make([]rune, r.Intn(8))
+		for i := range runes {
+			runes[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		return protoreflect.ValueOfString(string(runes))
+	case protoreflect.BytesKind:
+		data := make([]byte, r.Intn(8))
+		r.Read(data)
+		return protoreflect.ValueOfBytes(data)
+	case protoreflect.MessageKind:
+		if depth <= 0 {
+			return protoreflect.ValueOfMessage(dynamicpb.NewMessage(fd.Message()))
+		}
+		return protoreflect.ValueOfMessage(randomMessage(r, fd.Message(), depth-1))
+	}
+	panic("unsupported kind " + fd.Kind().String())
+}
+
+// randomBits favours the boundaries of every integer width since that is
+// where varint and zigzag encodings tend to break.
+func randomBits(r *rand.Rand) uint64 {
+	edges := []uint64{0, 1, math.MaxInt32, math.MaxUint32, math.MaxInt64, math.MaxUint64, 1 << 31, 1 << 63}
+	if r.Intn(4) == 0 {
+		return edges[r.Intn(len(edges))]
+	}
+	return r.Uint64() >> r.Intn(64)
+}
+
+func randomFloat(r *rand.Rand) float64 {
+	edges := []float64{0, math.Copysign(0, -1), 1, -1, math.MaxFloat32, math.SmallestNonzeroFloat32, math.Inf(1), math.Inf(-1)}
+	if r.Intn(4) == 0 {
+		return edges[r.Intn(len(edges))]
+	}
+	return r.NormFloat64() * 1e6
+}
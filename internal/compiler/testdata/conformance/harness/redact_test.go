@@ -0,0 +1,112 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func credentials(t *testing.T) *Credentials {
+	attachment, err := anypb.New(&Credentials{User: "cd", Secret: "s5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := anypb.New(attachment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Credentials{
+		User:        "root",
+		Secret:      "s0",
+		Delegate:    &Credentials{User: "ops", Secret: "s1"},
+		Chain:       []Credentials{{User: "ci", Secret: "s2"}},
+		Named:       map[string]*Credentials{"backup": {User: "backup", Secret: "s3"}},
+		Keys:        map[string]string{"k4": "s4"},
+		Attachment:  attachment,
+		Attachments: []*anypb.Any{nested},
+	}
+}
+
+// TestRedact checks that the sensitive fields of a message and of the
+// messages it contains are left out of every form it is printed or logged in.
+func TestRedact(t *testing.T) {
+	x := credentials(t)
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+	logger.Info("json", "request", x)
+	slog.New(slog.NewTextHandler(&buffer, nil)).Info("text", "request", x)
+
+	outputs := map[string]string{
+		"String":   x.String(),
+		"%v":       fmt.Sprintf("%v", x),
+		"%+v":      fmt.Sprintf("%+v", []*Credentials{x}),
+		"GoString": fmt.Sprintf("%#v", x),
+		"copy":     fmt.Sprintf("%v", *x),
+		"slog":     buffer.String(),
+	}
+	for name, output := range outputs {
+		for _, secret := range []string{"s0", "s1", "s2", "s3", "s4", "k4", "s5"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("%s output %s contains %q", name, output, secret)
+			}
+		}
+		for _, user := range []string{"root", "ops", "ci", "backup", "cd"} {
+			if !strings.Contains(output, user) {
+				t.Errorf("%s output %s does not contain %q", name, output, user)
+			}
+		}
+	}
+	if !strings.HasPrefix(outputs["GoString"], "Credentials{") {
+		t.Errorf("GoString = %s", outputs["GoString"])
+	}
+
+	data, err := x.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("s3")) {
+		t.Fatalf("MarshalJSON redacted the sensitive fields: %s", data)
+	}
+}
+
+// TestRedactElements checks that the elements of a repeated message field
+// and nested messages printed on their own are redacted as well.
+func TestRedactElements(t *testing.T) {
+	x := credentials(t)
+	for name, output := range map[string]string{
+		"repeated":  fmt.Sprintf("%v", x.Chain),
+		"+repeated": fmt.Sprintf("%+v", x.Chain),
+		"#repeated": fmt.Sprintf("%#v", x.Chain),
+		"element":   fmt.Sprintf("%v", x.Chain[0]),
+		"map":       fmt.Sprintf("%v", x.Named),
+		"nil":       fmt.Sprintf("%v", x.Delegate.Delegate),
+	} {
+		for _, secret := range []string{"s2", "s3"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("%s output %s contains %q", name, output, secret)
+			}
+		}
+	}
+}
+
+// TestRedactJSONErrors checks that the errors of malformed sensitive fields
+// do not carry their values.
+func TestRedactJSONErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"secret": ["s5"]}`,
+		`{"keys": {"k5": {"s5": true}}}`,
+	} {
+		err := new(Credentials).UnmarshalJSON([]byte(data))
+		if err == nil {
+			t.Errorf("UnmarshalJSON(%s) succeeded", data)
+			continue
+		}
+		if strings.Contains(err.Error(), "s5") {
+			t.Errorf("UnmarshalJSON(%s) error %q contains the value", data, err)
+		}
+	}
+}
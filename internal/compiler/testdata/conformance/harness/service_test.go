@@ -0,0 +1,121 @@
+package gen
+
+import (
+	"context"
+	"testing"
+)
+
+// tallyServer keeps the handler of the only rpc of Tallies.
+type tallyServer struct {
+	handler func(context.Context, *TalliesTransport[[]byte]) (*TalliesTransport[[]byte], error)
+}
+
+func (s *tallyServer) Start(TalliesServiceOptions) error { return nil }
+
+func (s *tallyServer) Stop() error { return nil }
+
+func (s *tallyServer) Handle(_ TalliesHandlerOptions, handler func(context.Context, *TalliesTransport[[]byte]) (*TalliesTransport[[]byte], error)) error {
+	s.handler = handler
+	return nil
+}
+
+// tallies returns response from Count, or a freshly acquired tally when it
+// is nil, and keeps the last response it returned.
+type tallies struct {
+	response *Tally
+	returned *Tally
+}
+
+func (t *tallies) Count(_ context.Context, in *TalliesTransport[*Batch], _ TalliesRpcOptions) (*TalliesTransport[*Tally], error) {
+	res := t.response
+	if res == nil {
+		res = AcquireTally()
+	}
+	res.Total = int64(len(in.Data.Items))
+	t.returned = res
+	return &TalliesTransport[*Tally]{Data: res}, nil
+}
+
+// releasingTallies opts into releasing the responses of Count.
+type releasingTallies struct {
+	tallies
+}
+
+func (t *releasingTallies) ReleaseResponse(rpc string) bool {
+	return rpc == "Count"
+}
+
+func count(t *testing.T, service TalliesService) *TalliesTransport[[]byte] {
+	t.Helper()
+	server := new(tallyServer)
+	BuildTallies(server, service)
+	in, err := (&Batch{Items: []Item{{Name: "a"}, {Name: "b"}}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := server.handler(context.Background(), &TalliesTransport[[]byte]{Data: in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(Tally)
+	if err := got.Unmarshal(out.Data); err != nil || got.Total != 2 {
+		t.Fatalf("Count returned %+v, %v", got, err)
+	}
+	return out
+}
+
+// TestHandlerKeepsResponses checks that the handlers leave the responses of
+// services that do not opt in alone, since they may be retained.
+func TestHandlerKeepsResponses(t *testing.T) {
+	kept := &Tally{}
+	count(t, &tallies{response: kept})
+	if kept.Total != 2 {
+		t.Fatal("the handler released a response the service did not opt in to release")
+	}
+}
+
+// TestHandlerReleasesResponses checks that the handlers release the
+// responses of the rpcs ReleaseResponse reports once encoded, and that the
+// encodings can be handed back to their pool.
+func TestHandlerReleasesResponses(t *testing.T) {
+	service := new(releasingTallies)
+	out := count(t, service)
+	if service.returned.Total != 0 {
+		t.Fatal("the handler did not release the response")
+	}
+	ReleaseTalliesEncoding(out)
+	if out.Data != nil {
+		t.Fatal("the released encoding is still referenced")
+	}
+}
+
+// TestReleaseClearsStorage checks that Release zeroes the storage it keeps
+// for the next acquired message and drops the storage of large messages.
+func TestReleaseClearsStorage(t *testing.T) {
+	x := AcquireBatch()
+	x.Items = append(x.Items, Item{Name: "a", Child: &Item{Name: "b"}})
+	x.SetUnknownFields([]byte{0x78, 0x01})
+	items, unknown := x.Items[:cap(x.Items)], x.UnknownFields()[:cap(x.UnknownFields())]
+	ReleaseBatch(x)
+	if cap(x.Items) == 0 || len(x.Items) != 0 {
+		t.Fatalf("Release did not keep the storage of the items: len %d, cap %d", len(x.Items), cap(x.Items))
+	}
+	for i := range items {
+		if items[i].Name != "" || items[i].Child != nil {
+			t.Fatalf("Release kept item %d alive: %+v", i, items[i])
+		}
+	}
+	for i, b := range unknown {
+		if b != 0 {
+			t.Fatalf("Release kept byte %d of the unknown fields", i)
+		}
+	}
+
+	large := AcquireBatch()
+	large.Items = make([]Item, 2048)
+	large.SetUnknownFields(make([]byte, 128<<10))
+	ReleaseBatch(large)
+	if large.Items != nil || large.UnknownFields() != nil {
+		t.Fatalf("Release kept the storage of a large message: cap %d items, cap %d bytes", cap(large.Items), cap(large.UnknownFields()))
+	}
+}
@@ -0,0 +1,46 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidateMapValues checks that Validate reports the rules broken by the
+// message values of a map under the key of the value.
+func TestValidateMapValues(t *testing.T) {
+	x := &Inventory{Stock: map[string]*Stock{"bolts": {Count: 3}, "nuts": {Count: -1}}}
+	err := x.Validate()
+	if err == nil {
+		t.Fatal("Validate did not report the invalid map value")
+	}
+	if !strings.Contains(err.Error(), "stock[nuts].count") || strings.Contains(err.Error(), "bolts") {
+		t.Fatalf("Validate = %v", err)
+	}
+	x.Stock["nuts"].Count = 0
+	if err := x.Validate(); err != nil {
+		t.Fatalf("Validate = %v", err)
+	}
+}
+
+// TestValidateUnset checks that the rules of unset fields are not checked,
+// other than required.
+func TestValidateUnset(t *testing.T) {
+	x := &Contact{Code: "ABC"}
+	if err := x.Validate(); err != nil {
+		t.Fatalf("Validate = %v", err)
+	}
+	err := new(Contact).Validate()
+	if err == nil || !strings.Contains(err.Error(), "code: is required") || strings.Contains(err.Error(), "pattern") {
+		t.Fatalf("Validate = %v, want only the missing code", err)
+	}
+	x = &Contact{Email: "nope", Handle: "bad", Age: 3, Code: "ABC"}
+	err = x.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted set fields that break their rules")
+	}
+	for _, field := range []string{"email", "handle", "age"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Validate = %v, want an error for %s", err, field)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package protos
+
+import "embed"
+
+// FS holds the protov option definitions so they can be imported as
+// "protov/<name>.proto" without a local installation.
+//
+//go:embed *.proto
+var FS embed.FS
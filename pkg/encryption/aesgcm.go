@@ -0,0 +1,57 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+var ErrInvalidCiphertext = errors.New("encryption: invalid ciphertext")
+
+// AESGCM is the reference FieldCipher. It seals values with AES-GCM under a
+// random nonce, which is prepended to the ciphertext, and authenticates the
+// name of the field as additional data. The key is held in memory; services
+// that keep their keys in a KMS implement FieldCipher around it instead.
+type AESGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM returns a cipher using key, which must be 16, 24 or 32 bytes
+// long to select AES-128, AES-192 or AES-256.
+func NewAESGCM(key []byte) (*AESGCM, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCM{aead: aead}, nil
+}
+
+func (c *AESGCM) Encrypt(field string, plaintext []byte) ([]byte, error) {
+	out := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(out); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(out, out, plaintext, []byte(field)), nil
+}
+
+func (c *AESGCM) Decrypt(field string, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < c.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, sealed := ciphertext[:c.aead.NonceSize()], ciphertext[c.aead.NonceSize():]
+	out, err := c.aead.Open(nil, nonce, sealed, []byte(field))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return out, nil
+}
+
+// Overhead is the length of the nonce and of the authentication tag.
+func (c *AESGCM) Overhead() int {
+	return c.aead.NonceSize() + c.aead.Overhead()
+}
@@ -0,0 +1,136 @@
+// Package encryption encrypts the fields marked with the protov.encrypted
+// option. Generated messages hold the plaintext of those fields and call the
+// FieldCipher registered with Register when encoding and decoding them, so
+// that their wire and JSON forms carry the ciphertext.
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// FieldCipher encrypts the values of encrypted fields.
+type FieldCipher interface {
+	// Encrypt returns the ciphertext of plaintext, which must be Overhead
+	// bytes longer. field is the full name of the field, such as
+	// "events.User.email", which the cipher should authenticate so that a
+	// ciphertext is only accepted by the field it was encrypted for.
+	Encrypt(field string, plaintext []byte) ([]byte, error)
+	// Decrypt returns the plaintext of a ciphertext returned by Encrypt
+	// for the same field.
+	Decrypt(field string, ciphertext []byte) ([]byte, error)
+	// Overhead is the number of bytes a ciphertext is longer than its
+	// plaintext, which the Size methods of generated messages count.
+	Overhead() int
+}
+
+var ErrNoCipher = errors.New("encryption: no FieldCipher is registered")
+
+// registered wraps the registered cipher, since atomic.Pointer cannot hold
+// an interface.
+type registered struct {
+	cipher FieldCipher
+}
+
+var _registered atomic.Pointer[registered]
+
+// Register sets the cipher used by every encrypted field, replacing the one
+// registered before. It is meant to be called once at startup, before
+// messages with encrypted fields are encoded or decoded.
+func Register(cipher FieldCipher) {
+	if cipher == nil {
+		_registered.Store(nil)
+		return
+	}
+	_registered.Store(&registered{cipher})
+}
+
+// Registered returns the registered cipher, or nil.
+func Registered() FieldCipher {
+	if r := _registered.Load(); r != nil {
+		return r.cipher
+	}
+	return nil
+}
+
+// EncryptBytes returns the ciphertext of the value of a bytes field. Empty
+// values are left empty, so that they are still omitted from the encoding.
+func EncryptBytes(field string, plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, nil
+	}
+	cipher := Registered()
+	if cipher == nil {
+		return nil, ErrNoCipher
+	}
+	out, err := cipher.Encrypt(field, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	if len(out) != len(plaintext)+cipher.Overhead() {
+		return nil, fmt.Errorf("encryption: the ciphertext of %s is %d bytes long, want %d", field, len(out), len(plaintext)+cipher.Overhead())
+	}
+	return out, nil
+}
+
+// DecryptBytes returns the plaintext of a ciphertext returned by
+// EncryptBytes.
+func DecryptBytes(field string, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 {
+		return nil, nil
+	}
+	cipher := Registered()
+	if cipher == nil {
+		return nil, ErrNoCipher
+	}
+	out, err := cipher.Decrypt(field, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+	return out, nil
+}
+
+// EncryptString returns the ciphertext of the value of a string field,
+// encoded with standard base64 so that it remains valid UTF-8 for the
+// runtimes that check string fields.
+func EncryptString(field string, plaintext string) (string, error) {
+	out, err := EncryptBytes(field, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// DecryptString returns the plaintext of a ciphertext returned by
+// EncryptString.
+func DecryptString(field string, ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("encryption: %w", err)
+	}
+	out, err := DecryptBytes(field, data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// BytesSize returns the length of the ciphertext EncryptBytes returns for a
+// plaintext of n bytes.
+func BytesSize(n int) int {
+	if n == 0 {
+		return 0
+	}
+	if cipher := Registered(); cipher != nil {
+		return n + cipher.Overhead()
+	}
+	return n
+}
+
+// StringSize returns the length of the ciphertext EncryptString returns for
+// a plaintext of n bytes.
+func StringSize(n int) int {
+	return base64.StdEncoding.EncodedLen(BytesSize(n))
+}
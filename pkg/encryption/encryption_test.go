@@ -0,0 +1,75 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAESGCM(t *testing.T) {
+	c, err := NewAESGCM(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := c.Encrypt("events.User.email", []byte("ada@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ciphertext) != len("ada@example.com")+c.Overhead() {
+		t.Fatalf("ciphertext is %d bytes long, want %d", len(ciphertext), len("ada@example.com")+c.Overhead())
+	}
+	plaintext, err := c.Decrypt("events.User.email", ciphertext)
+	if err != nil || string(plaintext) != "ada@example.com" {
+		t.Fatalf("Decrypt = %q, %v", plaintext, err)
+	}
+	if _, err := c.Decrypt("events.User.phone", ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("a ciphertext was accepted by another field: %v", err)
+	}
+	if _, err := c.Decrypt("events.User.email", ciphertext[:c.Overhead()-1]); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("a truncated ciphertext was accepted: %v", err)
+	}
+	if _, err := NewAESGCM(make([]byte, 20)); err == nil {
+		t.Fatal("a 20-byte key was accepted")
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	defer Register(Registered())
+	Register(nil)
+	if _, err := EncryptString("events.User.email", "ada@example.com"); !errors.Is(err, ErrNoCipher) {
+		t.Fatalf("EncryptString without a cipher: %v", err)
+	}
+	if out, err := EncryptBytes("events.User.key", nil); err != nil || out != nil {
+		t.Fatalf("EncryptBytes(nil) = %x, %v", out, err)
+	}
+
+	c, err := NewAESGCM(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Register(c)
+	ciphertext, err := EncryptString("events.User.email", "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ciphertext) != StringSize(len("ada@example.com")) {
+		t.Fatalf("ciphertext is %d bytes long, StringSize returned %d", len(ciphertext), StringSize(len("ada@example.com")))
+	}
+	if plaintext, err := DecryptString("events.User.email", ciphertext); err != nil || plaintext != "ada@example.com" {
+		t.Fatalf("DecryptString = %q, %v", plaintext, err)
+	}
+	if _, err := DecryptString("events.User.email", "not base64!"); err == nil {
+		t.Fatal("an invalid ciphertext was accepted")
+	}
+
+	data, err := EncryptBytes("events.User.key", []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != BytesSize(3) {
+		t.Fatalf("ciphertext is %d bytes long, BytesSize returned %d", len(data), BytesSize(3))
+	}
+	if plaintext, err := DecryptBytes("events.User.key", data); err != nil || !bytes.Equal(plaintext, []byte{1, 2, 3}) {
+		t.Fatalf("DecryptBytes = %x, %v", plaintext, err)
+	}
+}
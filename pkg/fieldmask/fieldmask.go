@@ -0,0 +1,55 @@
+package fieldmask
+
+import (
+	"sort"
+	"strings"
+)
+
+// Tree is a field mask parsed into the fields it selects, keyed by their
+// proto names. A nil subtree selects the whole field, while a non-nil one
+// only selects the listed fields of a message field.
+type Tree map[string]Tree
+
+// Parse builds the tree of the paths of a google.protobuf.FieldMask, which
+// are proto field names separated by dots. A path selecting a whole field
+// covers the paths into it, whichever comes first.
+func Parse(paths []string) Tree {
+	tree := make(Tree)
+	for _, path := range paths {
+		tree.add(strings.Split(path, "."))
+	}
+	return tree
+}
+
+func (t Tree) add(path []string) {
+	name := path[0]
+	if len(path) == 1 {
+		t[name] = nil
+		return
+	}
+	sub, ok := t[name]
+	if ok && sub == nil {
+		return
+	}
+	if sub == nil {
+		sub = make(Tree)
+		t[name] = sub
+	}
+	sub.add(path[1:])
+}
+
+// Paths returns the paths selected by the tree, in sorted order.
+func (t Tree) Paths() []string {
+	var paths []string
+	for name, sub := range t {
+		if sub == nil {
+			paths = append(paths, name)
+			continue
+		}
+		for _, path := range sub.Paths() {
+			paths = append(paths, name+"."+path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,39 @@
+package fieldmask
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  Tree
+	}{
+		{"empty", nil, Tree{}},
+		{"fields", []string{"id", "name"}, Tree{"id": nil, "name": nil}},
+		{"nested", []string{"route.path", "route.query", "id"}, Tree{"route": {"path": nil, "query": nil}, "id": nil}},
+		{"whole field first", []string{"route", "route.path"}, Tree{"route": nil}},
+		{"whole field last", []string{"route.path", "route"}, Tree{"route": nil}},
+		{"deep", []string{"a.b.c", "a.b.d", "a.e"}, Tree{"a": {"b": {"c": nil, "d": nil}, "e": nil}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Parse(test.paths); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Parse(%q) = %v, want %v", test.paths, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPaths(t *testing.T) {
+	tree := Parse([]string{"route.query", "id", "route.path", "a.b.c", "a.b"})
+	want := []string{"a.b", "id", "route.path", "route.query"}
+	if got := tree.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %q, want %q", got, want)
+	}
+	if got := Parse(nil).Paths(); len(got) != 0 {
+		t.Errorf("Paths() of an empty tree = %q", got)
+	}
+}
@@ -0,0 +1,223 @@
+package jsonpb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type (
+	// UnmarshalOptions configures how generated messages are read from JSON.
+	UnmarshalOptions struct {
+		// DiscardUnknown ignores members that do not match any field instead
+		// of failing.
+		DiscardUnknown bool
+	}
+
+	// Unmarshaler is implemented by every generated message.
+	Unmarshaler interface {
+		ReadJSON(value Value) error
+	}
+
+	// Value is a single undecoded JSON value. Scalars accept every form the
+	// proto3 JSON mapping allows, e.g. 64-bit integers as numbers or strings.
+	// Its errors never quote the value, which may belong to a sensitive
+	// field.
+	Value struct {
+		raw  json.RawMessage
+		opts UnmarshalOptions
+	}
+)
+
+var (
+	_null = []byte("null")
+)
+
+// Unmarshal reads the JSON encoding of the message.
+func Unmarshal(data []byte, m Unmarshaler, opts UnmarshalOptions) error {
+	return m.ReadJSON(Value{raw: bytes.TrimSpace(data), opts: opts})
+}
+
+// Key wraps an object key so that map keys can be parsed with the same
+// accessors as quoted values.
+func (v Value) Key(key string) Value {
+	raw, _ := json.Marshal(key)
+	return Value{raw: raw, opts: v.opts}
+}
+
+func (v Value) Options() UnmarshalOptions {
+	return v.opts
+}
+
+func (v Value) IsNull() bool {
+	return bytes.Equal(v.raw, _null)
+}
+
+func (v Value) Object() (map[string]Value, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(v.raw, &members); err != nil {
+		return nil, fmt.Errorf("expected object: %w", err)
+	}
+	out := make(map[string]Value, len(members))
+	for key, value := range members {
+		out[key] = Value{raw: value, opts: v.opts}
+	}
+	return out, nil
+}
+
+func (v Value) Array() ([]Value, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(v.raw, &items); err != nil {
+		return nil, fmt.Errorf("expected array: %w", err)
+	}
+	out := make([]Value, 0, len(items))
+	for _, item := range items {
+		out = append(out, Value{raw: item, opts: v.opts})
+	}
+	return out, nil
+}
+
+func (v Value) Bool() (bool, error) {
+	switch string(v.unquote()) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, errors.New("invalid bool")
+}
+
+func (v Value) Int32() (int32, error) {
+	value, err := v.integer(32)
+	return int32(value), err
+}
+
+func (v Value) Int64() (int64, error) {
+	return v.integer(64)
+}
+
+func (v Value) Uint32() (uint32, error) {
+	value, err := v.unsigned(32)
+	return uint32(value), err
+}
+
+func (v Value) Uint64() (uint64, error) {
+	return v.unsigned(64)
+}
+
+func (v Value) Float32() (float32, error) {
+	value, err := v.float(32)
+	return float32(value), err
+}
+
+func (v Value) Float64() (float64, error) {
+	return v.float(64)
+}
+
+func (v Value) String() (string, error) {
+	var value string
+	if err := json.Unmarshal(v.raw, &value); err != nil {
+		return "", fmt.Errorf("expected string: %w", err)
+	}
+	return value, nil
+}
+
+// Bytes accepts both the standard and the URL-safe base64 alphabets, with or
+// without padding.
+func (v Value) Bytes() ([]byte, error) {
+	value, err := v.String()
+	if err != nil {
+		return nil, err
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if data, err := encoding.DecodeString(value); err == nil {
+			return data, nil
+		}
+	}
+	return nil, errors.New("invalid base64 value")
+}
+
+func (v Value) Message(m Unmarshaler) error {
+	return m.ReadJSON(v)
+}
+
+// Proto reads messages backed by google.golang.org/protobuf, such as the
+// well-known types, from their canonical JSON representation.
+func (v Value) Proto(m proto.Message) error {
+	return protojson.UnmarshalOptions{DiscardUnknown: v.opts.DiscardUnknown}.Unmarshal(v.raw, m)
+}
+
+// Enum accepts either the name or the number of an enum value.
+func Enum[E ~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64](v Value, values map[string]E) (E, error) {
+	if name, err := v.String(); err == nil {
+		if value, ok := values[name]; ok {
+			return value, nil
+		}
+		return 0, errors.New("unknown enum value")
+	}
+	number, err := v.Int32()
+	if err != nil {
+		return 0, err
+	}
+	return E(number), nil
+}
+
+// UnknownField reports a member that does not match any field.
+func UnknownField(name string) error {
+	return fmt.Errorf("unknown field %q", name)
+}
+
+func (v Value) unquote() []byte {
+	if len(v.raw) >= 2 && v.raw[0] == '"' && v.raw[len(v.raw)-1] == '"' {
+		return v.raw[1 : len(v.raw)-1]
+	}
+	return v.raw
+}
+
+func (v Value) integer(bitSize int) (int64, error) {
+	raw := string(v.unquote())
+	if value, err := strconv.ParseInt(raw, 10, bitSize); err == nil {
+		return value, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value != math.Trunc(value) || value < -math.Pow(2, float64(bitSize-1)) || value >= math.Pow(2, float64(bitSize-1)) {
+		return 0, fmt.Errorf("invalid int%d value", bitSize)
+	}
+	return int64(value), nil
+}
+
+func (v Value) unsigned(bitSize int) (uint64, error) {
+	raw := string(v.unquote())
+	if value, err := strconv.ParseUint(raw, 10, bitSize); err == nil {
+		return value, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value != math.Trunc(value) || value < 0 || value >= math.Pow(2, float64(bitSize)) {
+		return 0, fmt.Errorf("invalid uint%d value", bitSize)
+	}
+	return uint64(value), nil
+}
+
+func (v Value) float(bitSize int) (float64, error) {
+	raw := string(v.unquote())
+	switch raw {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	value, err := strconv.ParseFloat(raw, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid float%d value", bitSize)
+	}
+	return value, nil
+}
@@ -0,0 +1,394 @@
+package jsonpb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Redacted replaces the value of sensitive fields in the String, GoString
+// and LogValue methods of generated messages.
+const Redacted = "[REDACTED]"
+
+type (
+	// MarshalOptions configures how generated messages are written as JSON.
+	MarshalOptions struct {
+		// EmitDefaults writes fields holding their zero value instead of
+		// omitting them.
+		EmitDefaults bool
+		// UseProtoNames writes the original proto field names instead of
+		// their lowerCamelCase JSON names.
+		UseProtoNames bool
+		// UseEnumNumbers writes enum values as numbers instead of names.
+		UseEnumNumbers bool
+		// Redact writes the fields marked with the sensitive option as
+		// Redacted, including those of nested messages, and replaces invalid
+		// UTF-8 instead of failing. It is meant for logs rather than for
+		// data that is read back.
+		Redact bool
+	}
+
+	// Marshaler is implemented by every generated message.
+	Marshaler interface {
+		WriteJSON(w *Writer) error
+	}
+
+	// Writer builds the canonical proto3 JSON representation of a message.
+	Writer struct {
+		buffer bytes.Buffer
+		opts   MarshalOptions
+		first  []bool
+		named  bool
+		err    error
+	}
+)
+
+// Marshal returns the JSON encoding of the message.
+func Marshal(m Marshaler, opts MarshalOptions) ([]byte, error) {
+	w := NewWriter(opts)
+	if err := m.WriteJSON(w); err != nil {
+		return nil, err
+	}
+	if err := w.Err(); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+func NewWriter(opts MarshalOptions) *Writer {
+	return &Writer{
+		opts: opts,
+	}
+}
+
+func (w *Writer) Bytes() []byte {
+	return w.buffer.Bytes()
+}
+
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) EmitDefaults() bool {
+	return w.opts.EmitDefaults
+}
+
+// Redact reports whether sensitive fields are to be written with
+// Redacted.
+func (w *Writer) Redact() bool {
+	return w.opts.Redact
+}
+
+// Redacted writes Redacted in place of the value of a sensitive field.
+func (w *Writer) Redacted() {
+	w.String(Redacted)
+}
+
+func (w *Writer) BeginObject() {
+	w.separate()
+	w.buffer.WriteByte('{')
+	w.first = append(w.first, true)
+}
+
+func (w *Writer) EndObject() {
+	w.first = w.first[:len(w.first)-1]
+	w.buffer.WriteByte('}')
+}
+
+func (w *Writer) BeginArray() {
+	w.separate()
+	w.buffer.WriteByte('[')
+	w.first = append(w.first, true)
+}
+
+func (w *Writer) EndArray() {
+	w.first = w.first[:len(w.first)-1]
+	w.buffer.WriteByte(']')
+}
+
+// Name writes the key of the next object member using either its JSON name
+// or its proto name depending on the options.
+func (w *Writer) Name(jsonName string, protoName string) {
+	if w.opts.UseProtoNames {
+		w.Key(protoName)
+		return
+	}
+	w.Key(jsonName)
+}
+
+// Key writes the key of the next object member as is.
+func (w *Writer) Key(key string) {
+	w.separate()
+	w.writeString(key)
+	w.buffer.WriteByte(':')
+	w.named = true
+}
+
+func (w *Writer) Null() {
+	w.separate()
+	w.buffer.WriteString("null")
+}
+
+func (w *Writer) Bool(value bool) {
+	w.separate()
+	w.buffer.WriteString(strconv.FormatBool(value))
+}
+
+func (w *Writer) Int32(value int64) {
+	w.separate()
+	w.buffer.WriteString(strconv.FormatInt(value, 10))
+}
+
+// Int64 writes 64-bit integers as strings as required by the JSON mapping.
+func (w *Writer) Int64(value int64) {
+	w.separate()
+	w.buffer.WriteByte('"')
+	w.buffer.WriteString(strconv.FormatInt(value, 10))
+	w.buffer.WriteByte('"')
+}
+
+func (w *Writer) Uint32(value uint64) {
+	w.separate()
+	w.buffer.WriteString(strconv.FormatUint(value, 10))
+}
+
+// Uint64 writes 64-bit integers as strings as required by the JSON mapping.
+func (w *Writer) Uint64(value uint64) {
+	w.separate()
+	w.buffer.WriteByte('"')
+	w.buffer.WriteString(strconv.FormatUint(value, 10))
+	w.buffer.WriteByte('"')
+}
+
+func (w *Writer) Float32(value float32) {
+	w.float(float64(value), 32)
+}
+
+func (w *Writer) Float64(value float64) {
+	w.float(value, 64)
+}
+
+func (w *Writer) String(value string) {
+	w.separate()
+	w.writeString(value)
+}
+
+func (w *Writer) Base64(value []byte) {
+	w.separate()
+	w.buffer.WriteByte('"')
+	w.buffer.WriteString(base64.StdEncoding.EncodeToString(value))
+	w.buffer.WriteByte('"')
+}
+
+// Enum writes the name of an enum value, falling back to its number for
+// values without a name.
+func (w *Writer) Enum(name string, number int64) {
+	if name == "" || w.opts.UseEnumNumbers {
+		w.Int32(number)
+		return
+	}
+	w.String(name)
+}
+
+func (w *Writer) Message(m Marshaler) {
+	if w.err != nil {
+		return
+	}
+	if err := m.WriteJSON(w); err != nil {
+		w.err = err
+	}
+}
+
+// Proto writes messages backed by google.golang.org/protobuf, such as the
+// well-known types, using their canonical JSON representation.
+func (w *Writer) Proto(m proto.Message) {
+	if w.err != nil {
+		return
+	}
+	if m, ok := m.(*anypb.Any); ok && w.opts.Redact {
+		w.redactAny(m)
+		return
+	}
+	w.protojson(m)
+}
+
+func (w *Writer) protojson(m proto.Message) {
+	data, err := protojson.MarshalOptions{
+		EmitUnpopulated: w.opts.EmitDefaults,
+		UseProtoNames:   w.opts.UseProtoNames,
+		UseEnumNumbers:  w.opts.UseEnumNumbers,
+	}.Marshal(m)
+	if err != nil {
+		w.err = err
+		return
+	}
+	w.separate()
+	w.buffer.Write(bytes.TrimSpace(data))
+}
+
+// redactAny writes the message carried by an Any through its WriteJSON
+// method, so that the sensitive fields of a generated message are redacted
+// there as well. A message that cannot be resolved is written as Redacted.
+func (w *Writer) redactAny(m *anypb.Any) {
+	inner, err := m.UnmarshalNew()
+	if err != nil {
+		w.Redacted()
+		return
+	}
+	switch inner := inner.(type) {
+	case Marshaler:
+		sub := NewWriter(w.opts)
+		if err := inner.WriteJSON(sub); err != nil || sub.err != nil {
+			w.Redacted()
+			return
+		}
+		// The fields of the message follow @type in the same object.
+		fields := sub.Bytes()
+		w.separate()
+		w.buffer.WriteString(`{"@type":`)
+		w.writeString(m.GetTypeUrl())
+		if len(fields) > 2 {
+			w.buffer.WriteByte(',')
+		}
+		w.buffer.Write(fields[1:])
+	case *anypb.Any:
+		w.BeginObject()
+		w.Key("@type")
+		w.String(m.GetTypeUrl())
+		w.Key("value")
+		w.redactAny(inner)
+		w.EndObject()
+	default:
+		w.protojson(m)
+	}
+}
+
+// ProtoString returns the JSON form of a message of the protobuf-go runtime,
+// such as a well-known type, as the String methods of generated messages
+// write it.
+func ProtoString(m proto.Message) string {
+	w := NewWriter(MarshalOptions{Redact: true})
+	w.Proto(m)
+	return string(w.Bytes())
+}
+
+// LogValue returns the value the LogValue methods of generated messages log
+// for a nested message: the LogValue of a generated message, the ProtoString
+// of any other message and nil for a nil message.
+func LogValue(m proto.Message) slog.Value {
+	if m == nil || !m.ProtoReflect().IsValid() {
+		return slog.AnyValue(nil)
+	}
+	if m, ok := m.(slog.LogValuer); ok {
+		return m.LogValue()
+	}
+	return slog.StringValue(ProtoString(m))
+}
+
+func (w *Writer) separate() {
+	if w.named {
+		w.named = false
+		return
+	}
+	if len(w.first) == 0 {
+		return
+	}
+	if w.first[len(w.first)-1] {
+		w.first[len(w.first)-1] = false
+		return
+	}
+	w.buffer.WriteByte(',')
+}
+
+func (w *Writer) float(value float64, bitSize int) {
+	w.separate()
+	switch {
+	case math.IsNaN(value):
+		w.buffer.WriteString(`"NaN"`)
+		return
+	case math.IsInf(value, 1):
+		w.buffer.WriteString(`"Infinity"`)
+		return
+	case math.IsInf(value, -1):
+		w.buffer.WriteString(`"-Infinity"`)
+		return
+	}
+	format := byte('f')
+	if abs := math.Abs(value); abs != 0 {
+		if bitSize == 64 && (abs < 1e-6 || abs >= 1e21) || bitSize == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	w.buffer.WriteString(strconv.FormatFloat(value, format, -1, bitSize))
+}
+
+func (w *Writer) writeString(value string) {
+	if !utf8.ValidString(value) {
+		if w.opts.Redact {
+			value = strings.ToValidUTF8(value, string(utf8.RuneError))
+		} else if w.err == nil {
+			w.err = errors.New("invalid UTF-8 in string")
+		}
+	}
+	w.buffer.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			w.buffer.WriteString(`\"`)
+		case '\\':
+			w.buffer.WriteString(`\\`)
+		case '\n':
+			w.buffer.WriteString(`\n`)
+		case '\r':
+			w.buffer.WriteString(`\r`)
+		case '\t':
+			w.buffer.WriteString(`\t`)
+		case '\b':
+			w.buffer.WriteString(`\b`)
+		case '\f':
+			w.buffer.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&w.buffer, `\u%04x`, r)
+				continue
+			}
+			w.buffer.WriteRune(r)
+		}
+	}
+	w.buffer.WriteByte('"')
+}
+
+// SortedKeys returns the keys of a map field in ascending order so that the
+// JSON output is deterministic.
+func SortedKeys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := reflect.ValueOf(keys[i]), reflect.ValueOf(keys[j])
+		switch a.Kind() {
+		case reflect.Bool:
+			return !a.Bool() && b.Bool()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return a.Uint() < b.Uint()
+		default:
+			return a.String() < b.String()
+		}
+	})
+	return keys
+}
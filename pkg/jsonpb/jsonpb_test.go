@@ -0,0 +1,149 @@
+package jsonpb
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestWriter(t *testing.T) {
+	w := NewWriter(MarshalOptions{UseProtoNames: true})
+	w.BeginObject()
+	w.Name("userId", "user_id")
+	w.Int64(-5)
+	w.Name("tags", "tags")
+	w.BeginArray()
+	w.String("a\n")
+	w.Float64(math.NaN())
+	w.Float32(1e-7)
+	w.EndArray()
+	w.Name("labels", "labels")
+	w.BeginObject()
+	w.Key("1")
+	w.Enum("", 3)
+	w.Key("2")
+	w.Base64([]byte{0xff})
+	w.EndObject()
+	w.EndObject()
+
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"user_id":"-5","tags":["a\n","NaN",1e-07],"labels":{"1":3,"2":"/w=="}}`
+	if got := string(w.Bytes()); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestWriterRedact(t *testing.T) {
+	w := NewWriter(MarshalOptions{Redact: true})
+	w.BeginObject()
+	w.Name("password", "password")
+	if w.Redact() {
+		w.Redacted()
+	}
+	w.Name("name", "name")
+	w.String("a\xffb")
+	w.EndObject()
+
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"password":"[REDACTED]","name":"a` + "\ufffd" + `b"}`
+	if got := string(w.Bytes()); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	if NewWriter(MarshalOptions{}).Redact() {
+		t.Fatal("Redact is set without the option")
+	}
+}
+
+// TestProtoStringAny checks that an Any is written through protojson when it
+// carries a message without sensitive fields, and as Redacted when its type
+// cannot be resolved.
+func TestProtoStringAny(t *testing.T) {
+	duration, err := anypb.New(durationpb.New(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ProtoString(duration); !strings.Contains(got, `"1s"`) {
+		t.Errorf("ProtoString(%v) = %s", duration, got)
+	}
+	unknown := &anypb.Any{TypeUrl: "type.googleapis.com/missing.Secret", Value: []byte("s0")}
+	if got := ProtoString(unknown); got != `"[REDACTED]"` {
+		t.Errorf("ProtoString of an unresolved Any = %s", got)
+	}
+}
+
+func TestValueScalars(t *testing.T) {
+	value := func(raw string) Value {
+		return Value{raw: []byte(raw)}
+	}
+
+	if v, err := value(`"9007199254740993"`).Int64(); err != nil || v != 9007199254740993 {
+		t.Errorf("quoted int64: got %d, %v", v, err)
+	}
+	if v, err := value(`1e2`).Int32(); err != nil || v != 100 {
+		t.Errorf("exponent int32: got %d, %v", v, err)
+	}
+	if _, err := value(`1.5`).Int32(); err == nil {
+		t.Error("fractional int32: expected error")
+	}
+	if _, err := value(`4294967296`).Uint32(); err == nil {
+		t.Error("overflowing uint32: expected error")
+	}
+	if v, err := value(`"-Infinity"`).Float64(); err != nil || !math.IsInf(v, -1) {
+		t.Errorf("-Infinity: got %v, %v", v, err)
+	}
+	if v, err := value(`"_w"`).Bytes(); err != nil || len(v) != 1 || v[0] != 0xff {
+		t.Errorf("url-safe base64: got %v, %v", v, err)
+	}
+	if v, err := Enum(value(`"B"`), map[string]uint{"A": 0, "B": 1}); err != nil || v != 1 {
+		t.Errorf("enum name: got %d, %v", v, err)
+	}
+	if v, err := Enum(value(`7`), map[string]uint{"A": 0}); err != nil || v != 7 {
+		t.Errorf("enum number: got %d, %v", v, err)
+	}
+	if v, err := value(`{}`).Key("true").Bool(); err != nil || !v {
+		t.Errorf("bool key: got %v, %v", v, err)
+	}
+}
+
+func TestValueErrorsOmitValue(t *testing.T) {
+	value := func(raw string) Value {
+		return Value{raw: []byte(raw)}
+	}
+	errs := make([]error, 0)
+	read := func(_ any, err error) {
+		errs = append(errs, err)
+	}
+	read(value(`"s3cr3t"`).Bool())
+	read(value(`"s3cr3t"`).Int32())
+	read(value(`"s3cr3t"`).Uint64())
+	read(value(`"s3cr3t"`).Float32())
+	read(value(`"s3cr3t!"`).Bytes())
+	read(Enum(value(`"s3cr3t"`), map[string]int32{"A": 0}))
+
+	w := NewWriter(MarshalOptions{})
+	w.String("s3cr3t\xff")
+	errs = append(errs, w.Err())
+
+	for _, err := range errs {
+		if err == nil {
+			t.Error("expected error")
+		} else if strings.Contains(err.Error(), "s3cr3t") {
+			t.Errorf("error %q contains the value", err)
+		}
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	keys := SortedKeys(map[int]string{10: "", -1: "", 9: ""})
+	if keys[0] != -1 || keys[1] != 9 || keys[2] != 10 {
+		t.Fatalf("unexpected order %v", keys)
+	}
+}
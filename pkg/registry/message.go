@@ -0,0 +1,401 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var _methods = &protoiface.Methods{
+	Marshal:          marshal,
+	Unmarshal:        unmarshal,
+	CheckInitialized: checkInitialized,
+}
+
+type (
+	// message is a protoreflect.Message over a generated message. Reads are
+	// served from a dynamic copy decoded from x on first use, and every
+	// write is encoded back into x so that both always hold the same value.
+	// The writes that merging their encoding into x carries out, such as
+	// appending to a list or setting a scalar, only decode that encoding;
+	// the others encode the whole copy again.
+	//
+	// Copying fails when x cannot be encoded, e.g. when it has an encrypted
+	// field and no cipher is registered, or when x rejects a value written
+	// through reflection. The reflective methods cannot return errors, so
+	// they go on with what was copied and err is reported by Marshal and
+	// CheckInitialized, which protojson and prototext call as well.
+	message struct {
+		x         Message
+		typ       protoreflect.MessageType
+		unmarshal func([]byte) error
+		valid     bool
+		dynamic   *dynamicpb.Message
+		err       error
+	}
+
+	// nested, list and mapValue are the mutable values handed out by
+	// message, which write the root message back after every change. fd is
+	// the field of the root message holding a list or map, and nil for those
+	// of nested messages.
+	nested struct {
+		protoreflect.Message
+		root *message
+	}
+
+	list struct {
+		protoreflect.List
+		root *message
+		fd   protoreflect.FieldDescriptor
+	}
+
+	mapValue struct {
+		protoreflect.Map
+		root *message
+		fd   protoreflect.FieldDescriptor
+	}
+)
+
+// MessageOf returns the reflective view of x used to implement ProtoReflect.
+// mt is the type returned by RegisterMessage and unmarshal merges the wire
+// format into x like proto.Merge does.
+//
+// The view copies x through the wire format, so it is meant for
+// interoperability rather than speed: proto.Marshal and proto.Unmarshal call
+// Marshal and unmarshal directly and stay fast, while protojson, prototext
+// and field-by-field reflection pay for the copies.
+func MessageOf(x Message, mt protoreflect.MessageType, unmarshal func([]byte) error) protoreflect.Message {
+	return &message{
+		x:         x,
+		typ:       mt,
+		unmarshal: unmarshal,
+		valid:     x != nil && !reflect.ValueOf(x).IsNil(),
+	}
+}
+
+func (m *message) load() *dynamicpb.Message {
+	if m.dynamic != nil {
+		return m.dynamic
+	}
+	m.dynamic = dynamicpb.NewMessage(m.typ.Descriptor())
+	if !m.valid {
+		return m.dynamic
+	}
+	data, err := m.x.Marshal()
+	if err != nil {
+		m.fail("marshal", err)
+		return m.dynamic
+	}
+	if err := (proto.UnmarshalOptions{AllowPartial: true}).Unmarshal(data, m.dynamic); err != nil {
+		m.fail("unmarshal", err)
+	}
+	return m.dynamic
+}
+
+func (m *message) store() {
+	if !m.valid {
+		panic(fmt.Sprintf("registry: cannot modify an invalid %s", m.typ.Descriptor().FullName()))
+	}
+	dynamic := m.load()
+	if m.err != nil {
+		// x was not copied, so writing the copy back would lose it.
+		return
+	}
+	data, err := proto.MarshalOptions{AllowPartial: true}.Marshal(dynamic)
+	if err != nil {
+		m.fail("marshal", err)
+		return
+	}
+	previous, err := m.x.Marshal()
+	if err != nil {
+		m.fail("marshal", err)
+		return
+	}
+	m.x.Reset()
+	if err := m.unmarshal(data); err != nil {
+		m.fail("unmarshal", err)
+		// Restore the value x held before the rejected write.
+		m.x.Reset()
+		_ = m.unmarshal(previous)
+	}
+}
+
+// merge writes back a write to the copy by decoding into x only the
+// encoding of part, which holds the value written. It is used for the
+// writes that merging carries out, so that writing a field does not encode
+// the whole message again.
+func (m *message) merge(part *dynamicpb.Message) {
+	if !m.valid {
+		panic(fmt.Sprintf("registry: cannot modify an invalid %s", m.typ.Descriptor().FullName()))
+	}
+	if m.err != nil {
+		return
+	}
+	data, err := proto.MarshalOptions{AllowPartial: true}.Marshal(part)
+	if err != nil {
+		m.fail("marshal", err)
+		return
+	}
+	if err := m.unmarshal(data); err != nil {
+		m.fail("unmarshal", err)
+	}
+}
+
+// set writes v to field fd of the copy. Setting a scalar that the encoding
+// carries, or a message field that was unset, is merged into x.
+func (m *message) set(fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	dynamic := m.load()
+	mergeable := !fd.IsList() && !fd.IsMap() && !inOneof(fd) && (fd.Message() == nil || !dynamic.Has(fd))
+	dynamic.Set(fd, v)
+	if !mergeable {
+		m.store()
+		return
+	}
+	part := dynamicpb.NewMessage(m.Descriptor())
+	part.Set(fd, v)
+	if !part.Has(fd) {
+		// A zero scalar has no encoding to merge.
+		m.store()
+		return
+	}
+	m.merge(part)
+}
+
+// inOneof reports whether fd is a member of a oneof, whose other members a
+// write clears.
+func inOneof(fd protoreflect.FieldDescriptor) bool {
+	od := fd.ContainingOneof()
+	return od != nil && !od.IsSynthetic()
+}
+
+// fail records the first error of copying the message.
+func (m *message) fail(op string, err error) {
+	if m.err == nil {
+		m.err = fmt.Errorf("registry: failed to %s %s: %w", op, m.typ.Descriptor().FullName(), err)
+	}
+}
+
+// wrap makes the composite value v of field fd write m back when it changes.
+// Lists and maps of the root message are given fd, so that their writes
+// can be merged.
+func (m *message) wrap(fd protoreflect.FieldDescriptor, v protoreflect.Value, root bool) protoreflect.Value {
+	field := fd
+	if !root {
+		field = nil
+	}
+	switch {
+	case fd.IsList():
+		return protoreflect.ValueOfList(&list{v.List(), m, field})
+	case fd.IsMap():
+		return protoreflect.ValueOfMap(&mapValue{v.Map(), m, field})
+	default:
+		return protoreflect.ValueOfMessage(&nested{v.Message(), m})
+	}
+}
+
+// unwrap returns the value held by a value returned from wrap.
+func unwrap(v protoreflect.Value) protoreflect.Value {
+	switch value := v.Interface().(type) {
+	case *list:
+		return protoreflect.ValueOfList(value.List)
+	case *mapValue:
+		return protoreflect.ValueOfMap(value.Map)
+	case *nested:
+		return protoreflect.ValueOfMessage(value.Message)
+	}
+	return v
+}
+
+func (m *message) Descriptor() protoreflect.MessageDescriptor {
+	return m.typ.Descriptor()
+}
+
+func (m *message) Type() protoreflect.MessageType {
+	return m.typ
+}
+
+func (m *message) New() protoreflect.Message {
+	return m.typ.New()
+}
+
+func (m *message) Interface() protoreflect.ProtoMessage {
+	return m.x
+}
+
+func (m *message) Range(f func(protoreflect.FieldDescriptor, protoreflect.Value) bool) {
+	m.load().Range(f)
+}
+
+func (m *message) Has(fd protoreflect.FieldDescriptor) bool {
+	return m.load().Has(fd)
+}
+
+func (m *message) Clear(fd protoreflect.FieldDescriptor) {
+	m.load().Clear(fd)
+	m.store()
+}
+
+func (m *message) Get(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	return m.load().Get(fd)
+}
+
+func (m *message) Set(fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	m.set(fd, unwrap(v))
+}
+
+// Mutable only writes back the message it creates for an unset message
+// field, as an empty list or map or an existing message leave the encoding
+// unchanged.
+func (m *message) Mutable(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	dynamic := m.load()
+	if fd.IsList() || fd.IsMap() || dynamic.Has(fd) {
+		return m.wrap(fd, dynamic.Mutable(fd), true)
+	}
+	m.set(fd, dynamic.NewField(fd))
+	return m.wrap(fd, dynamic.Mutable(fd), true)
+}
+
+func (m *message) NewField(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	return m.load().NewField(fd)
+}
+
+func (m *message) WhichOneof(od protoreflect.OneofDescriptor) protoreflect.FieldDescriptor {
+	return m.load().WhichOneof(od)
+}
+
+func (m *message) GetUnknown() protoreflect.RawFields {
+	return m.load().GetUnknown()
+}
+
+func (m *message) SetUnknown(raw protoreflect.RawFields) {
+	m.load().SetUnknown(raw)
+	m.store()
+}
+
+func (m *message) IsValid() bool {
+	return m.valid
+}
+
+func (m *message) ProtoMethods() *protoiface.Methods {
+	return _methods
+}
+
+func marshal(in protoiface.MarshalInput) (protoiface.MarshalOutput, error) {
+	m := in.Message.(*message)
+	if m.err != nil {
+		return protoiface.MarshalOutput{}, m.err
+	}
+	if !m.valid {
+		return protoiface.MarshalOutput{Buf: in.Buf}, nil
+	}
+	data, err := m.x.Marshal()
+	if err != nil {
+		return protoiface.MarshalOutput{}, err
+	}
+	return protoiface.MarshalOutput{Buf: append(in.Buf, data...)}, nil
+}
+
+// unmarshal merges in.Buf into the message by decoding it after the current
+// encoding, where later fields override or extend earlier ones.
+func unmarshal(in protoiface.UnmarshalInput) (protoiface.UnmarshalOutput, error) {
+	m := in.Message.(*message)
+	data, err := m.x.Marshal()
+	if err != nil {
+		return protoiface.UnmarshalOutput{}, err
+	}
+	m.x.Reset()
+	m.dynamic = nil
+	return protoiface.UnmarshalOutput{}, m.unmarshal(append(data, in.Buf...))
+}
+
+// checkInitialized reports the error of copying the message, which the
+// reflective reads of protojson and prototext would otherwise hide, and the
+// required fields it is missing.
+func checkInitialized(in protoiface.CheckInitializedInput) (protoiface.CheckInitializedOutput, error) {
+	m := in.Message.(*message)
+	dynamic := m.load()
+	if m.err != nil {
+		return protoiface.CheckInitializedOutput{}, m.err
+	}
+	return protoiface.CheckInitializedOutput{}, proto.CheckInitialized(dynamic)
+}
+
+func (n *nested) Clear(fd protoreflect.FieldDescriptor) {
+	n.Message.Clear(fd)
+	n.root.store()
+}
+
+func (n *nested) Set(fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	n.Message.Set(fd, unwrap(v))
+	n.root.store()
+}
+
+func (n *nested) Mutable(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	v := n.Message.Mutable(fd)
+	n.root.store()
+	return n.root.wrap(fd, v, false)
+}
+
+func (n *nested) SetUnknown(raw protoreflect.RawFields) {
+	n.Message.SetUnknown(raw)
+	n.root.store()
+}
+
+func (l *list) Set(i int, v protoreflect.Value) {
+	l.List.Set(i, unwrap(v))
+	l.root.store()
+}
+
+// Append merges the element into x when the list is a field of the root
+// message, which appends it there as well.
+func (l *list) Append(v protoreflect.Value) {
+	v = unwrap(v)
+	l.List.Append(v)
+	if l.fd == nil {
+		l.root.store()
+		return
+	}
+	part := dynamicpb.NewMessage(l.root.Descriptor())
+	part.Mutable(l.fd).List().Append(v)
+	l.root.merge(part)
+}
+
+func (l *list) AppendMutable() protoreflect.Value {
+	v := l.List.AppendMutable()
+	l.root.store()
+	return protoreflect.ValueOfMessage(&nested{v.Message(), l.root})
+}
+
+func (l *list) Truncate(n int) {
+	l.List.Truncate(n)
+	l.root.store()
+}
+
+func (m *mapValue) Clear(key protoreflect.MapKey) {
+	m.Map.Clear(key)
+	m.root.store()
+}
+
+// Set merges the entry into x when the map is a field of the root message,
+// which replaces the value of key there as well.
+func (m *mapValue) Set(key protoreflect.MapKey, v protoreflect.Value) {
+	v = unwrap(v)
+	m.Map.Set(key, v)
+	if m.fd == nil {
+		m.root.store()
+		return
+	}
+	part := dynamicpb.NewMessage(m.root.Descriptor())
+	part.Mutable(m.fd).Map().Set(key, v)
+	m.root.merge(part)
+}
+
+func (m *mapValue) Mutable(key protoreflect.MapKey) protoreflect.Value {
+	v := m.Map.Mutable(key)
+	m.root.store()
+	return protoreflect.ValueOfMessage(&nested{v.Message(), m.root})
+}
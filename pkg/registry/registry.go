@@ -0,0 +1,99 @@
+// Package registry connects generated messages to the protobuf-go runtime.
+// Generated files register their descriptors with protoregistry.GlobalFiles
+// and their message types with protoregistry.GlobalTypes, and implement
+// ProtoReflect with MessageOf so that they can be passed to protojson,
+// prototext, gRPC reflection and the other protobuf-go APIs.
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Message is implemented by every generated message.
+type Message interface {
+	protoreflect.ProtoMessage
+	Marshal() ([]byte, error)
+	Reset()
+}
+
+type messageType struct {
+	desc protoreflect.MessageDescriptor
+	new  func() Message
+}
+
+// RegisterFile builds the serialized FileDescriptorProto in rawDesc against
+// the files registered so far and adds it to protoregistry.GlobalFiles. deps
+// are the descriptors of the files it imports from the same Go package;
+// they are not read, but taking them as arguments makes Go register them
+// first. Imports that are not registered are replaced with placeholders.
+// It panics if the descriptor is invalid or its path is already registered.
+func RegisterFile(rawDesc []byte, deps ...protoreflect.FileDescriptor) protoreflect.FileDescriptor {
+	fdp := new(descriptorpb.FileDescriptorProto)
+	if err := proto.Unmarshal(rawDesc, fdp); err != nil {
+		panic(fmt.Errorf("registry: invalid descriptor: %w", err))
+	}
+	fd, err := protodesc.FileOptions{AllowUnresolvable: true}.New(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(fmt.Errorf("registry: invalid descriptor for %s: %w", fdp.GetName(), err))
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(fd); err != nil {
+		panic(fmt.Errorf("registry: %w", err))
+	}
+	return fd
+}
+
+// RegisterMessage adds the message called name in file to
+// protoregistry.GlobalTypes. new returns an empty instance of its Go type.
+// It panics if file does not declare the message or the name is already
+// registered.
+func RegisterMessage(file protoreflect.FileDescriptor, name protoreflect.FullName, new func() Message) protoreflect.MessageType {
+	desc := findMessage(file, name)
+	if desc == nil {
+		panic(fmt.Errorf("registry: %s does not declare %s", file.Path(), name))
+	}
+	mt := &messageType{desc: desc, new: new}
+	if err := protoregistry.GlobalTypes.RegisterMessage(mt); err != nil {
+		panic(fmt.Errorf("registry: %w", err))
+	}
+	return mt
+}
+
+func findMessage(file protoreflect.FileDescriptor, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	relative := string(name)
+	if pkg := file.Package(); pkg != "" {
+		var ok bool
+		if relative, ok = strings.CutPrefix(relative, string(pkg)+"."); !ok {
+			return nil
+		}
+	}
+	messages := file.Messages()
+	var desc protoreflect.MessageDescriptor
+	for _, part := range strings.Split(relative, ".") {
+		if desc = messages.ByName(protoreflect.Name(part)); desc == nil {
+			return nil
+		}
+		messages = desc.Messages()
+	}
+	return desc
+}
+
+func (t *messageType) New() protoreflect.Message {
+	return t.new().ProtoReflect()
+}
+
+func (t *messageType) Zero() protoreflect.Message {
+	zero := reflect.Zero(reflect.TypeOf(t.new())).Interface().(Message)
+	return MessageOf(zero, t, nil)
+}
+
+func (t *messageType) Descriptor() protoreflect.MessageDescriptor {
+	return t.desc
+}
@@ -0,0 +1,303 @@
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/vedadiyan/protov/pkg/encryption"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	_testFile  = RegisterFile(testDescriptor())
+	_pointType = RegisterMessage(_testFile, "registrytest.Point", func() Message { return new(point) })
+)
+
+// point keeps its wire encoding, which is all MessageOf needs from a
+// generated message.
+type point struct {
+	data []byte
+}
+
+func (x *point) ProtoReflect() protoreflect.Message {
+	return MessageOf(x, _pointType, func(data []byte) error {
+		x.data = append(x.data, data...)
+		return nil
+	})
+}
+
+func (x *point) Marshal() ([]byte, error) {
+	return x.data, nil
+}
+
+func (x *point) Reset() {
+	x.data = nil
+}
+
+// sealed cannot be encoded, like a message with an encrypted field when no
+// cipher is registered.
+type sealed struct{}
+
+func (x *sealed) ProtoReflect() protoreflect.Message {
+	return MessageOf(x, _pointType, func([]byte) error { return nil })
+}
+
+func (x *sealed) Marshal() ([]byte, error) {
+	_, err := encryption.EncryptString("registrytest.Point.name", "a")
+	return nil, err
+}
+
+func (x *sealed) Reset() {}
+
+func testDescriptor() []byte {
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		out := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     typ.Enum(),
+		}
+		if typeName != "" {
+			out.TypeName = proto.String(typeName)
+		}
+		return out
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("registry_test.proto"),
+		Package: proto.String("registrytest"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Point"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("x", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				field("name", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("tags", 3, repeated, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				field("children", 4, repeated, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".registrytest.Point.ChildrenEntry"),
+				field("next", 5, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".registrytest.Point"),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("ChildrenEntry"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("key", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("value", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".registrytest.Point"),
+				},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}},
+		}},
+	}
+	data, err := proto.Marshal(fdp)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// newPoint returns a point holding the encoding of the text format message.
+func newPoint(t *testing.T, text string) *point {
+	t.Helper()
+	dynamic := dynamicpb.NewMessage(_pointType.Descriptor())
+	if err := prototext.Unmarshal([]byte(text), dynamic); err != nil {
+		t.Fatal(err)
+	}
+	data, err := proto.Marshal(dynamic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &point{data: data}
+}
+
+// decode returns the value held by x.
+func decode(t *testing.T, x *point) *dynamicpb.Message {
+	t.Helper()
+	dynamic := dynamicpb.NewMessage(_pointType.Descriptor())
+	if err := proto.Unmarshal(x.data, dynamic); err != nil {
+		t.Fatal(err)
+	}
+	return dynamic
+}
+
+func TestRegister(t *testing.T) {
+	fd, err := protoregistry.GlobalFiles.FindFileByPath("registry_test.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fd != _testFile {
+		t.Error("registered file differs from the one returned by RegisterFile")
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName("registrytest.Point")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mt.New().Interface().(*point); !ok {
+		t.Errorf("New returned %T, want *point", mt.New().Interface())
+	}
+	if zero := mt.Zero(); zero.IsValid() || zero.Has(mt.Descriptor().Fields().ByName("x")) {
+		t.Error("Zero returned a valid or populated message")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	in := newPoint(t, `x: 1 name: "a" tags: [1, 2] children { key: "b" value { x: 2 } } next { name: "c" }`)
+	data, err := protojson.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(point)
+	if err := protojson.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(in, out) {
+		t.Errorf("round trip through %s changed the message:\n got %v\nwant %v", data, decode(t, out), decode(t, in))
+	}
+	text, err := prototext.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := new(point)
+	if err := prototext.Unmarshal(text, again); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(in, again) {
+		t.Errorf("round trip through %s changed the message", text)
+	}
+}
+
+func TestWritesAreStored(t *testing.T) {
+	x := newPoint(t, `x: 1 tags: [1]`)
+	m := x.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	m.Set(fields.ByName("name"), protoreflect.ValueOfString("a"))
+	m.Clear(fields.ByName("x"))
+	m.Mutable(fields.ByName("tags")).List().Append(protoreflect.ValueOfInt32(2))
+	child := m.Mutable(fields.ByName("children")).Map().Mutable(protoreflect.ValueOfString("b").MapKey()).Message()
+	child.Set(fields.ByName("x"), protoreflect.ValueOfInt32(3))
+	m.Mutable(fields.ByName("next")).Message().Set(fields.ByName("name"), protoreflect.ValueOfString("c"))
+
+	want := newPoint(t, `name: "a" tags: [1, 2] children { key: "b" value { x: 3 } } next { name: "c" }`)
+	if got := decode(t, x); !proto.Equal(got, decode(t, want)) {
+		t.Errorf("got %v, want %v", got, decode(t, want))
+	}
+}
+
+// TestWritesReplace checks the writes that merging their encoding into the
+// message would not carry out: replacing a message or a list and setting a
+// scalar to its zero value.
+func TestWritesReplace(t *testing.T) {
+	x := newPoint(t, `name: "a" tags: [1] next { x: 1 }`)
+	m := x.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	next := m.NewField(fields.ByName("next"))
+	next.Message().Set(fields.ByName("name"), protoreflect.ValueOfString("c"))
+	m.Set(fields.ByName("next"), next)
+	tags := m.NewField(fields.ByName("tags"))
+	tags.List().Append(protoreflect.ValueOfInt32(2))
+	m.Set(fields.ByName("tags"), tags)
+	m.Set(fields.ByName("name"), protoreflect.ValueOfString(""))
+	m.Mutable(fields.ByName("children")).Map().Set(protoreflect.ValueOfString("b").MapKey(), next)
+	m.Mutable(fields.ByName("children")).Map().Set(protoreflect.ValueOfString("b").MapKey(), protoreflect.ValueOfMessage(m.New()))
+
+	want := newPoint(t, `tags: [2] children { key: "b" value {} } next { name: "c" }`)
+	if got := decode(t, x); !proto.Equal(got, decode(t, want)) {
+		t.Errorf("got %v, want %v", got, decode(t, want))
+	}
+}
+
+// BenchmarkAppend appends thousands of elements to a repeated field through
+// reflection, which must not encode the whole message for every element.
+func BenchmarkAppend(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		list := new(point).ProtoReflect().Mutable(_pointType.Descriptor().Fields().ByName("tags")).List()
+		for j := 0; j < 4096; j++ {
+			list.Append(protoreflect.ValueOfInt32(int32(j)))
+		}
+	}
+}
+
+func TestWireMethods(t *testing.T) {
+	x := newPoint(t, `x: 1 tags: [1]`)
+	data, err := proto.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(x.data) {
+		t.Errorf("proto.Marshal returned %x, want %x", data, x.data)
+	}
+	if size := proto.Size(x); size != len(x.data) {
+		t.Errorf("proto.Size returned %d, want %d", size, len(x.data))
+	}
+
+	other := newPoint(t, `name: "a" tags: [2]`)
+	if err := (proto.UnmarshalOptions{Merge: true}).Unmarshal(other.data, x); err != nil {
+		t.Fatal(err)
+	}
+	want := newPoint(t, `x: 1 name: "a" tags: [1, 2]`)
+	if got := decode(t, x); !proto.Equal(got, decode(t, want)) {
+		t.Errorf("merge: got %v, want %v", got, decode(t, want))
+	}
+	if err := proto.Unmarshal(other.data, x); err != nil {
+		t.Fatal(err)
+	}
+	if got := decode(t, x); !proto.Equal(got, decode(t, other)) {
+		t.Errorf("unmarshal: got %v, want %v", got, decode(t, other))
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	x := new(sealed)
+	m := x.ProtoReflect()
+	name := m.Descriptor().Fields().ByName("name")
+	if m.Has(name) || m.Get(name).String() != "" {
+		t.Error("a message that cannot be encoded has fields")
+	}
+	m.Range(func(protoreflect.FieldDescriptor, protoreflect.Value) bool {
+		t.Error("a message that cannot be encoded has fields")
+		return false
+	})
+
+	if _, err := proto.Marshal(x); !errors.Is(err, encryption.ErrNoCipher) {
+		t.Errorf("proto.Marshal error = %v, want %v", err, encryption.ErrNoCipher)
+	}
+	if _, err := protojson.Marshal(x); !errors.Is(err, encryption.ErrNoCipher) {
+		t.Errorf("protojson.Marshal error = %v, want %v", err, encryption.ErrNoCipher)
+	}
+	if _, err := prototext.Marshal(x); !errors.Is(err, encryption.ErrNoCipher) {
+		t.Errorf("prototext.Marshal error = %v, want %v", err, encryption.ErrNoCipher)
+	}
+}
+
+func TestRejectedWrite(t *testing.T) {
+	x := newPoint(t, `x: 1`)
+	rejected := errors.New("rejected")
+	m := MessageOf(x, _pointType, func(data []byte) error {
+		if bytes.Contains(data, []byte("rejected")) {
+			return rejected
+		}
+		x.data = append(x.data, data...)
+		return nil
+	})
+	m.Set(m.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("rejected"))
+
+	if got, want := decode(t, x), decode(t, newPoint(t, `x: 1`)); !proto.Equal(got, want) {
+		t.Errorf("a rejected write changed the message to %v", got)
+	}
+	if _, err := m.ProtoMethods().CheckInitialized(protoiface.CheckInitializedInput{Message: m}); !errors.Is(err, rejected) {
+		t.Errorf("CheckInitialized error = %v, want %v", err, rejected)
+	}
+	if _, err := m.ProtoMethods().Marshal(protoiface.MarshalInput{Message: m}); !errors.Is(err, rejected) {
+		t.Errorf("Marshal error = %v, want %v", err, rejected)
+	}
+}
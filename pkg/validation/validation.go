@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type (
+	// FieldError reports a single constraint violation together with the
+	// path of the offending field, e.g. "address.city" or "tags[2]".
+	FieldError struct {
+		Path   string
+		Reason string
+	}
+
+	// Errors is the list of violations returned by a generated Validate method.
+	Errors []*FieldError
+)
+
+func NewFieldError(path string, reason string) *FieldError {
+	return &FieldError{
+		Path:   path,
+		Reason: reason,
+	}
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + ": " + e.Reason
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Append adds the violations of a nested message to the list, prefixing each
+// path with the path of the field holding the message.
+func (e Errors) Append(prefix string, err error) Errors {
+	if err == nil {
+		return e
+	}
+	var nested Errors
+	if errors.As(err, &nested) {
+		for _, fieldError := range nested {
+			e = append(e, NewFieldError(join(prefix, fieldError.Path), fieldError.Reason))
+		}
+		return e
+	}
+	var fieldError *FieldError
+	if errors.As(err, &fieldError) {
+		return append(e, NewFieldError(join(prefix, fieldError.Path), fieldError.Reason))
+	}
+	return append(e, NewFieldError(prefix, err.Error()))
+}
+
+// Err returns nil when there are no violations so that an empty list never
+// ends up as a non-nil error interface.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func IsEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	if err != nil {
+		return false
+	}
+	return address.Address == value
+}
+
+func IsUUID(value string) bool {
+	_, err := uuid.Parse(value)
+	return err == nil
+}
+
+func join(prefix string, path string) string {
+	if path == "" {
+		return prefix
+	}
+	if strings.HasPrefix(path, "[") {
+		return prefix + path
+	}
+	return prefix + "." + path
+}
@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorsAppend(t *testing.T) {
+	nested := Errors{
+		NewFieldError("city", "is required"),
+		NewFieldError("tags[0]", "must match pattern"),
+	}
+
+	var errs Errors
+	errs = errs.Append("address", nested.Err())
+	errs = errs.Append("items[1]", NewFieldError("", "is required"))
+	errs = errs.Append("other", errors.New("failed"))
+	errs = errs.Append("ignored", nil)
+
+	want := []string{"address.city", "address.tags[0]", "items[1]", "other"}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, path := range want {
+		if errs[i].Path != path {
+			t.Errorf("error %d: expected path %q, got %q", i, path, errs[i].Path)
+		}
+	}
+}
+
+func TestErrorsErr(t *testing.T) {
+	var errs Errors
+	if err := errs.Err(); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(string) bool
+		value string
+		want  bool
+	}{
+		{"email", IsEmail, "user@example.com", true},
+		{"email with name", IsEmail, "User <user@example.com>", false},
+		{"email empty", IsEmail, "", false},
+		{"uuid", IsUUID, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", true},
+		{"uuid invalid", IsUUID, "6ba7b810", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.check(tt.value); got != tt.want {
+				t.Errorf("expected %v for %q, got %v", tt.want, tt.value, got)
+			}
+		})
+	}
+}
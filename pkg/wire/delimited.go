@@ -0,0 +1,71 @@
+package wire
+
+import (
+	"bufio"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Marshaler is implemented by the generated messages.
+type Marshaler interface {
+	Size() int
+	MarshalAppend(b []byte) ([]byte, error)
+}
+
+// Unmarshaler is implemented by the generated messages. Unmarshal must copy
+// what it keeps of data, which DelimitedReader reuses.
+type Unmarshaler interface {
+	Reset()
+	Unmarshal(data []byte) error
+}
+
+// DelimitedWriter writes a stream of messages, each prefixed with its length
+// as a varint. It is the framing of protodelim and of writeDelimitedTo in the
+// other protobuf runtimes, suited to log and batch files.
+type DelimitedWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func NewDelimitedWriter(w io.Writer) *DelimitedWriter {
+	return &DelimitedWriter{w: w}
+}
+
+// Write encodes m to the stream. The encoding buffer is reused across calls.
+func (dw *DelimitedWriter) Write(m Marshaler) error {
+	b := protowire.AppendVarint(dw.buf[:0], uint64(m.Size()))
+	b, err := m.MarshalAppend(b)
+	if err != nil {
+		return err
+	}
+	dw.buf = b
+	_, err = dw.w.Write(b)
+	return err
+}
+
+// DelimitedReader reads a stream of messages written by DelimitedWriter.
+type DelimitedReader struct {
+	streamReader
+}
+
+func NewDelimitedReader(r io.Reader) *DelimitedReader {
+	return &DelimitedReader{streamReader{r: bufio.NewReader(r)}}
+}
+
+// Read resets m and decodes the next message of the stream into it. It
+// returns io.EOF once the stream ends between two messages and
+// io.ErrUnexpectedEOF when it ends inside one.
+func (dr *DelimitedReader) Read(m Unmarshaler) error {
+	dr.buf = dr.buf[:0]
+	length, err := dr.readVarint()
+	if err != nil {
+		return err
+	}
+	dr.buf = dr.buf[:0]
+	if err := dr.readN(length); err != nil {
+		return err
+	}
+	m.Reset()
+	return m.Unmarshal(dr.buf)
+}
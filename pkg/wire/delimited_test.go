@@ -0,0 +1,63 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// rawMessage stands for a generated message whose encoding is its data.
+type rawMessage struct {
+	data []byte
+}
+
+func (m *rawMessage) Size() int {
+	return len(m.data)
+}
+
+func (m *rawMessage) MarshalAppend(b []byte) ([]byte, error) {
+	return append(b, m.data...), nil
+}
+
+func (m *rawMessage) Reset() {
+	m.data = nil
+}
+
+func (m *rawMessage) Unmarshal(data []byte) error {
+	m.data = append(m.data, data...)
+	return nil
+}
+
+func TestDelimited(t *testing.T) {
+	messages := [][]byte{[]byte("first"), nil, bytes.Repeat([]byte{0xff}, 300)}
+	var stream bytes.Buffer
+	dw := NewDelimitedWriter(&stream)
+	for _, data := range messages {
+		if err := dw.Write(&rawMessage{data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dr := NewDelimitedReader(&stream)
+	m := &rawMessage{[]byte("stale")}
+	for i, want := range messages {
+		if err := dr.Read(m); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if !bytes.Equal(m.data, want) {
+			t.Fatalf("message %d: read %x, want %x", i, m.data, want)
+		}
+	}
+	if err := dr.Read(m); err != io.EOF {
+		t.Fatalf("expected io.EOF after the last message, got %v", err)
+	}
+}
+
+func TestDelimitedTruncated(t *testing.T) {
+	for _, data := range [][]byte{{0x05, 'a'}, {0x80}} {
+		if err := NewDelimitedReader(bytes.NewReader(data)).Read(new(rawMessage)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("reading %x: expected io.ErrUnexpectedEOF, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package wire
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// _chunkSize bounds how much of a length-delimited value is allocated ahead
+// of reading it, so that a corrupt length fails at the end of the stream
+// rather than with an oversized allocation.
+const _chunkSize = 64 << 10
+
+// _maxDepth bounds the nesting of groups, as protowire does.
+const _maxDepth = 10000
+
+var (
+	ErrTooLarge = errors.New("wire: value is too large")
+	ErrTooDeep  = errors.New("wire: groups are nested too deeply")
+	// ErrInvalidUTF8 is returned by the generated decoders for proto3
+	// strings that are not valid UTF-8.
+	ErrInvalidUTF8 = errors.New("wire: invalid UTF-8")
+)
+
+// Field is a field read from a stream by FieldReader.
+type Field struct {
+	Number protowire.Number
+	Type   protowire.Type
+	// Raw holds the tag and the value of the field as they were encoded.
+	Raw []byte
+	// Value holds the value of the field without its tag, nor its length
+	// prefix for protowire.BytesType.
+	Value []byte
+}
+
+// streamReader buffers the field or message being read from a stream.
+type streamReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// readVarint appends the next varint of the stream to the buffer and
+// returns its value.
+func (s *streamReader) readVarint() (uint64, error) {
+	start := len(s.buf)
+	for i := 0; i < protowire.SizeVarint(math.MaxUint64); i++ {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(s.buf) != start {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		s.buf = append(s.buf, b)
+		if b < 0x80 {
+			break
+		}
+	}
+	v, n := protowire.ConsumeVarint(s.buf[start:])
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	return v, nil
+}
+
+// readN appends the next n bytes of the stream to the buffer.
+func (s *streamReader) readN(n uint64) error {
+	if n > math.MaxInt-uint64(len(s.buf)) {
+		return ErrTooLarge
+	}
+	for n != 0 {
+		chunk := int(min(n, _chunkSize))
+		start := len(s.buf)
+		s.buf = slices.Grow(s.buf, chunk)[:start+chunk]
+		if _, err := io.ReadFull(s.r, s.buf[start:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		n -= uint64(chunk)
+	}
+	return nil
+}
+
+// FieldReader reads the fields of an encoded message from a stream one at a
+// time, so that a message larger than memory can be decoded field by field.
+type FieldReader struct {
+	streamReader
+}
+
+func NewFieldReader(r io.Reader) *FieldReader {
+	return &FieldReader{streamReader{r: bufio.NewReader(r)}}
+}
+
+// Next reads the next field of the stream. The slices of the field are only
+// valid until the following call. It returns io.EOF once the stream ends
+// between two fields and io.ErrUnexpectedEOF when it ends inside one.
+func (fr *FieldReader) Next() (Field, error) {
+	fr.buf = fr.buf[:0]
+	num, typ, err := fr.readField(0)
+	if err != nil {
+		return Field{}, err
+	}
+	if typ == protowire.EndGroupType {
+		return Field{}, protowire.ParseError(protowire.ConsumeFieldValue(num, typ, nil))
+	}
+	_, _, n := protowire.ConsumeTag(fr.buf)
+	field := Field{Number: num, Type: typ, Raw: fr.buf, Value: fr.buf[n:]}
+	if typ == protowire.BytesType {
+		_, m := protowire.ConsumeVarint(field.Value)
+		field.Value = field.Value[m:]
+	}
+	return field, nil
+}
+
+// readField appends the tag and the value of the next field to the buffer,
+// reading groups up to their end tag.
+func (fr *FieldReader) readField(depth int) (protowire.Number, protowire.Type, error) {
+	start := len(fr.buf)
+	if _, err := fr.readVarint(); err != nil {
+		return 0, 0, err
+	}
+	num, typ, n := protowire.ConsumeTag(fr.buf[start:])
+	if n < 0 {
+		return 0, 0, protowire.ParseError(n)
+	}
+	var err error
+	switch typ {
+	case protowire.VarintType:
+		_, err = fr.readVarint()
+	case protowire.Fixed32Type:
+		err = fr.readN(4)
+	case protowire.Fixed64Type:
+		err = fr.readN(8)
+	case protowire.BytesType:
+		var length uint64
+		if length, err = fr.readVarint(); err == nil {
+			err = fr.readN(length)
+		}
+	case protowire.StartGroupType:
+		err = fr.readGroup(num, depth+1)
+	case protowire.EndGroupType:
+	default:
+		return 0, 0, protowire.ParseError(protowire.ConsumeFieldValue(num, typ, nil))
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return num, typ, err
+}
+
+// readGroup appends the fields of the group started by num to the buffer,
+// including its end tag.
+func (fr *FieldReader) readGroup(num protowire.Number, depth int) error {
+	if depth > _maxDepth {
+		return ErrTooDeep
+	}
+	for {
+		n, typ, err := fr.readField(depth)
+		if err != nil {
+			return err
+		}
+		if typ == protowire.EndGroupType {
+			if n != num {
+				return fmt.Errorf("wire: group %d ended by the end tag of %d", num, n)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestFieldReader(t *testing.T) {
+	var group []byte
+	group = protowire.AppendTag(group, 1, protowire.VarintType)
+	group = protowire.AppendVarint(group, 1)
+	group = protowire.AppendTag(group, 4, protowire.EndGroupType)
+
+	fields := []struct {
+		num   protowire.Number
+		typ   protowire.Type
+		value []byte
+	}{
+		{1, protowire.VarintType, protowire.AppendVarint(nil, 150)},
+		{2, protowire.BytesType, bytes.Repeat([]byte("abc"), _chunkSize)},
+		{3, protowire.Fixed32Type, protowire.AppendFixed32(nil, 7)},
+		{4, protowire.StartGroupType, group},
+		{5, protowire.Fixed64Type, protowire.AppendFixed64(nil, 9)},
+	}
+	var data []byte
+	for _, field := range fields {
+		data = protowire.AppendTag(data, field.num, field.typ)
+		if field.typ == protowire.BytesType {
+			data = protowire.AppendVarint(data, uint64(len(field.value)))
+		}
+		data = append(data, field.value...)
+	}
+
+	fr := NewFieldReader(iotest.OneByteReader(bytes.NewReader(data)))
+	var raw []byte
+	for _, want := range fields {
+		field, err := fr.Next()
+		if err != nil {
+			t.Fatalf("field %d: %v", want.num, err)
+		}
+		if field.Number != want.num || field.Type != want.typ {
+			t.Fatalf("read field %d of type %d, want %d of type %d", field.Number, field.Type, want.num, want.typ)
+		}
+		if !bytes.Equal(field.Value, want.value) {
+			t.Fatalf("field %d: value %x, want %x", want.num, field.Value, want.value)
+		}
+		raw = append(raw, field.Raw...)
+	}
+	if _, err := fr.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF after the last field, got %v", err)
+	}
+	if !bytes.Equal(raw, data) {
+		t.Fatal("the raw fields do not add up to the stream")
+	}
+}
+
+func TestFieldReaderErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"truncated tag":    {0x80},
+		"truncated varint": {0x08, 0x96},
+		"truncated bytes":  {0x12, 0x05, 'a'},
+		"truncated group":  {0x0b, 0x10, 0x01},
+		"mismatched group": {0x0b, 0x14},
+		"end group":        {0x0c},
+		"reserved type":    {0x0e},
+		"field zero":       {0x00},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewFieldReader(bytes.NewReader(data)).Next()
+			if err == nil || err == io.EOF {
+				t.Fatalf("expected an error, got %v", err)
+			}
+		})
+	}
+	if _, err := NewFieldReader(bytes.NewReader([]byte{0x0a, 0x01})).Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func FuzzFieldReader(f *testing.F) {
+	f.Add([]byte{0x08, 0x96, 0x01, 0x12, 0x03, 'a', 'b', 'c'})
+	f.Add([]byte{0x0b, 0x08, 0x01, 0x0c, 0x1d, 0x01, 0x02, 0x03, 0x04})
+	f.Add([]byte{0x12, 0x05, 'a'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fr := NewFieldReader(bytes.NewReader(data))
+		rest := data
+		for {
+			field, err := fr.Next()
+			num, typ, n := protowire.ConsumeField(rest)
+			if err != nil {
+				if err == io.EOF && len(rest) != 0 {
+					t.Fatalf("io.EOF with %x left", rest)
+				}
+				if err != io.EOF && n >= 0 {
+					t.Fatalf("rejected the field %x accepted by protowire: %v", rest[:n], err)
+				}
+				return
+			}
+			if n < 0 {
+				t.Fatalf("accepted the field %x rejected by protowire", field.Raw)
+			}
+			if field.Number != num || field.Type != typ || !bytes.Equal(field.Raw, rest[:n]) {
+				t.Fatalf("read %x as field %d of type %d, protowire read %x", field.Raw, field.Number, field.Type, rest[:n])
+			}
+			rest = rest[n:]
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package wire
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The wire types of protolizer's metadata.WireType. The types a tag carries
+// keep the numbering of the protobuf encoding; the zigzag types, which
+// protolizer records for sint32 and sint64 fields, follow them.
+const (
+	protolizerVarint     = 0
+	protolizerFixed64    = 1
+	protolizerBytes      = 2
+	protolizerStartGroup = 3
+	protolizerEndGroup   = 4
+	protolizerFixed32    = 5
+	protolizerZigZag32   = 6
+	protolizerZigZag64   = 7
+)
+
+// Type returns the protobuf wire type of a protolizer wire type. The zigzag
+// types are encoded as varints.
+func Type(wireType int) (protowire.Type, error) {
+	switch wireType {
+	case protolizerVarint, protolizerZigZag32, protolizerZigZag64:
+		return protowire.VarintType, nil
+	case protolizerFixed64:
+		return protowire.Fixed64Type, nil
+	case protolizerBytes:
+		return protowire.BytesType, nil
+	case protolizerStartGroup:
+		return protowire.StartGroupType, nil
+	case protolizerEndGroup:
+		return protowire.EndGroupType, nil
+	case protolizerFixed32:
+		return protowire.Fixed32Type, nil
+	}
+	return 0, fmt.Errorf("wire: invalid wire type %d", wireType)
+}
+
+// AppendUnknown consumes the value of a field that the message does not
+// declare from the buffer and appends it to dst together with its tag, so
+// that it can be written back verbatim when the message is encoded again.
+// wireType is the protolizer wire type of the field.
+func AppendUnknown(dst []byte, num int32, wireType int, buffer *bytes.Buffer) ([]byte, error) {
+	typ, err := Type(wireType)
+	if err != nil {
+		return dst, err
+	}
+	n := protowire.ConsumeFieldValue(protowire.Number(num), typ, buffer.Bytes())
+	if n < 0 {
+		return dst, protowire.ParseError(n)
+	}
+	dst = protowire.AppendTag(dst, protowire.Number(num), typ)
+	return append(dst, buffer.Next(n)...), nil
+}
@@ -0,0 +1,97 @@
+package wire
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestAppendUnknown(t *testing.T) {
+	var raw []byte
+	raw = protowire.AppendVarint(raw, 150)
+	raw = protowire.AppendBytes(raw, []byte("abc"))
+	raw = protowire.AppendFixed32(raw, 7)
+	buffer := bytes.NewBuffer(raw)
+
+	var dst []byte
+	var err error
+	fields := []struct {
+		num      int32
+		wireType protowire.Type
+	}{
+		{1, protowire.VarintType},
+		{2, protowire.BytesType},
+		{3, protowire.Fixed32Type},
+	}
+	for _, field := range fields {
+		if dst, err = AppendUnknown(dst, field.num, int(field.wireType), buffer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buffer.Len() != 0 {
+		t.Fatalf("expected buffer to be consumed, %d bytes left", buffer.Len())
+	}
+
+	var want []byte
+	want = protowire.AppendTag(want, 1, protowire.VarintType)
+	want = protowire.AppendVarint(want, 150)
+	want = protowire.AppendTag(want, 2, protowire.BytesType)
+	want = protowire.AppendBytes(want, []byte("abc"))
+	want = protowire.AppendTag(want, 3, protowire.Fixed32Type)
+	want = protowire.AppendFixed32(want, 7)
+	if !bytes.Equal(dst, want) {
+		t.Fatalf("expected %x, got %x", want, dst)
+	}
+}
+
+func TestAppendUnknownZigZag(t *testing.T) {
+	buffer := bytes.NewBuffer(protowire.AppendVarint(nil, protowire.EncodeZigZag(-3)))
+	dst, err := AppendUnknown(nil, 4, protolizerZigZag64, buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := protowire.AppendTag(nil, 4, protowire.VarintType)
+	want = protowire.AppendVarint(want, protowire.EncodeZigZag(-3))
+	if !bytes.Equal(dst, want) {
+		t.Fatalf("expected %x, got %x", want, dst)
+	}
+	if _, err := AppendUnknown(nil, 4, 8, buffer); err == nil {
+		t.Fatal("expected error for invalid wire type")
+	}
+}
+
+func TestAppendUnknownTruncated(t *testing.T) {
+	buffer := bytes.NewBuffer([]byte{0x05, 'a'})
+	if _, err := AppendUnknown(nil, 1, int(protowire.BytesType), buffer); err == nil {
+		t.Fatal("expected error for truncated value")
+	}
+}
+
+func FuzzAppendUnknown(f *testing.F) {
+	f.Add(int32(1), uint8(protowire.VarintType), []byte{0x96, 0x01})
+	f.Add(int32(2), uint8(protowire.BytesType), []byte{0x03, 'a', 'b', 'c'})
+	f.Add(int32(3), uint8(protowire.StartGroupType), []byte{0x08, 0x01, 0x1c})
+	f.Add(int32(4), uint8(protowire.Fixed64Type), []byte{0x01})
+	f.Fuzz(func(t *testing.T, num int32, wireType uint8, data []byte) {
+		if num < int32(protowire.MinValidNumber) || num > int32(protowire.MaxValidNumber) {
+			return
+		}
+		buffer := bytes.NewBuffer(append([]byte(nil), data...))
+		dst, err := AppendUnknown(nil, num, int(wireType), buffer)
+		if err != nil {
+			if buffer.Len() != len(data) {
+				t.Fatalf("buffer was consumed on error: %d of %d bytes left", buffer.Len(), len(data))
+			}
+			return
+		}
+		want, _ := Type(int(wireType))
+		n, typ, m := protowire.ConsumeField(dst)
+		if m != len(dst) || n != protowire.Number(num) || typ != want {
+			t.Fatalf("appended field %x does not round trip as field %d of type %d", dst, num, wireType)
+		}
+		if consumed := len(data) - buffer.Len(); !bytes.Equal(dst[len(dst)-consumed:], data[:consumed]) {
+			t.Fatalf("appended value %x does not match the consumed input %x", dst, data[:consumed])
+		}
+	})
+}